@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logTimestampLayout is the timestamp layout written at the start of each log line
+const logTimestampLayout = "2006/01/02 15:04:05"
+
+var (
+	// ansiPattern matches the ANSI color codes written to log lines
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+	// logLinePattern matches a log line: timestamp, level, optional component, and message
+	logLinePattern = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) (?:\[(\w+)\] )?(?:(\[\w+\]) )?(.*)$`)
+
+	// sensorSpeedPattern matches a logged BLE sensor speed (e.g., "BLE sensor speed: 9.28 mph")
+	sensorSpeedPattern = regexp.MustCompile(`BLE sensor speed: ([0-9.]+) (\S+)`)
+)
+
+// logSummary holds the diagnostic summary derived from a captured application log
+type logSummary struct {
+	lines       int
+	start       time.Time
+	end         time.Time
+	warnings    int
+	errors      int
+	scans       int
+	connections int
+	speedCount  int
+	speedSum    float64
+	maxSpeed    float64
+	speedUnits  string
+	lastError   string
+	markers     []string
+}
+
+// reconnections returns the number of connections made after the first
+func (s logSummary) reconnections() int {
+	return max(s.connections-1, 0)
+}
+
+// averageSpeed returns the average of the logged (non-zero) sensor speeds
+func (s logSummary) averageSpeed() float64 {
+
+	if s.speedCount == 0 {
+		return 0.0
+	}
+
+	return s.speedSum / float64(s.speedCount)
+}
+
+// analyzeLogFile parses a captured application log file and prints its diagnostic summary
+func analyzeLogFile(path string, out io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	summary, err := analyzeLog(file)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprint(out, formatLogSummary(summary))
+
+	return err
+}
+
+// analyzeLog reconstructs connection events and speed statistics from application log lines
+func analyzeLog(r io.Reader) (logSummary, error) {
+	var summary logSummary
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := ansiPattern.ReplaceAllString(scanner.Text(), "")
+
+		match := logLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		summary.lines++
+
+		if timestamp, err := time.Parse(logTimestampLayout, match[1]); err == nil {
+
+			if summary.start.IsZero() {
+				summary.start = timestamp
+			}
+
+			summary.end = timestamp
+		}
+
+		level, message := match[2], match[4]
+
+		switch level {
+		case "WARN":
+			summary.warnings++
+		case "ERROR", "FATAL":
+			summary.errors++
+			summary.lastError = message
+		}
+
+		switch {
+		case strings.HasPrefix(message, "now scanning the ether for BLE peripheral"):
+			summary.scans++
+		case strings.HasPrefix(message, "BLE peripheral device connected"):
+			summary.connections++
+		case strings.HasPrefix(message, "ride marker at"):
+			summary.markers = append(summary.markers, message)
+		}
+
+		if speedMatch := sensorSpeedPattern.FindStringSubmatch(message); speedMatch != nil {
+			recordLoggedSpeed(&summary, speedMatch[1], speedMatch[2])
+		}
+
+	}
+
+	return summary, scanner.Err()
+}
+
+// recordLoggedSpeed adds a logged sensor speed to the speed statistics (stops are not counted)
+func recordLoggedSpeed(summary *logSummary, value, units string) {
+	speed, err := strconv.ParseFloat(value, 64)
+	if err != nil || speed <= 0 {
+		return
+	}
+
+	summary.speedCount++
+	summary.speedSum += speed
+	summary.maxSpeed = max(summary.maxSpeed, speed)
+	summary.speedUnits = units
+}
+
+// formatLogSummary formats the diagnostic summary for printing
+func formatLogSummary(s logSummary) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "log lines: %d\n", s.lines)
+
+	if !s.start.IsZero() {
+		fmt.Fprintf(&b, "time span: %s to %s (%s)\n", s.start.Format(logTimestampLayout), s.end.Format(logTimestampLayout), s.end.Sub(s.start))
+	}
+
+	fmt.Fprintf(&b, "warnings: %d, errors: %d\n", s.warnings, s.errors)
+
+	if s.lastError != "" {
+		fmt.Fprintf(&b, "last error: %s\n", s.lastError)
+	}
+
+	fmt.Fprintf(&b, "BLE scans: %d, connections: %d, reconnections: %d\n", s.scans, s.connections, s.reconnections())
+
+	if len(s.markers) > 0 {
+		fmt.Fprintf(&b, "ride markers: %d\n", len(s.markers))
+
+		for _, marker := range s.markers {
+			fmt.Fprintf(&b, "  %s\n", marker)
+		}
+
+	}
+
+	if s.speedCount == 0 {
+		b.WriteString("sensor speeds: none logged (is logging_level set to \"info\" or \"debug\"?)\n")
+	} else {
+		fmt.Fprintf(&b, "sensor speeds: %d readings, average %.2f %s, max %.2f %s\n", s.speedCount, s.averageSpeed(), s.speedUnits, s.maxSpeed, s.speedUnits)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sampleLog is a captured log with a reconnection, a warning, an error, and a ride marker
+const sampleLog = "2024/12/16 15:08:56 Starting BLE Sync Cycle 0.6.2\n" +
+	"2024/12/16 15:08:56 \x1b[32m[INFO]\x1b[0m [BLE] now scanning the ether for BLE peripheral UUID of F1:42:D8:DE:35:16...\n" +
+	"2024/12/16 15:08:58 \x1b[32m[INFO]\x1b[0m [BLE] BLE peripheral device connected\n" +
+	"2024/12/16 15:09:01 \x1b[32m[INFO]\x1b[0m [SPEED] \x1b[34mBLE sensor speed: 0.00 mph\x1b[0m\n" +
+	"2024/12/16 15:09:02 \x1b[32m[INFO]\x1b[0m [SPEED] \x1b[34mBLE sensor speed: 10.00 mph\x1b[0m\n" +
+	"2024/12/16 15:09:03 \x1b[33m[WARN]\x1b[0m [SPEED] possible sensor glitch: speed changed by 20.00 m/s per second\n" +
+	"2024/12/16 15:09:04 \x1b[32m[INFO]\x1b[0m [SPEED] \x1b[34mBLE sensor speed: 20.00 mph\x1b[0m\n" +
+	"2024/12/16 15:09:05 \x1b[31m[ERROR]\x1b[0m [BLE] scan error: adapter busy\n" +
+	"2024/12/16 15:09:06 \x1b[32m[INFO]\x1b[0m [BLE] now scanning the ether for BLE peripheral UUID of F1:42:D8:DE:35:16...\n" +
+	"2024/12/16 15:09:08 \x1b[32m[INFO]\x1b[0m [BLE] BLE peripheral device connected (rear wheel)\n" +
+	"2024/12/16 15:09:09 \x1b[32m[INFO]\x1b[0m [VIDEO] ride marker at 15:09:09, distance 0.02 mi\n" +
+	"2024/12/16 15:09:10 \x1b[32m[INFO]\x1b[0m [SPEED] \x1b[34mBLE sensor speed: 15.00 mph\x1b[0m\n" +
+	"not a log line\n"
+
+// TestAnalyzeLog tests the diagnostic summary derived from a captured log
+func TestAnalyzeLog(t *testing.T) {
+	summary, err := analyzeLog(strings.NewReader(sampleLog))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 12, summary.lines)
+	assert.Equal(t, 14*time.Second, summary.end.Sub(summary.start))
+	assert.Equal(t, 1, summary.warnings)
+	assert.Equal(t, 1, summary.errors)
+	assert.Equal(t, "scan error: adapter busy", summary.lastError)
+	assert.Equal(t, 2, summary.scans)
+	assert.Equal(t, 2, summary.connections)
+	assert.Equal(t, 1, summary.reconnections())
+	assert.Equal(t, 3, summary.speedCount)
+	assert.InDelta(t, 15.0, summary.averageSpeed(), 0.001)
+	assert.InDelta(t, 20.0, summary.maxSpeed, 0.001)
+	assert.Equal(t, "mph", summary.speedUnits)
+	assert.Equal(t, []string{"ride marker at 15:09:09, distance 0.02 mi"}, summary.markers)
+
+	report := formatLogSummary(summary)
+	assert.Contains(t, report, "BLE scans: 2, connections: 2, reconnections: 1")
+	assert.Contains(t, report, "ride markers: 1\n  ride marker at 15:09:09, distance 0.02 mi\n")
+	assert.Contains(t, report, "sensor speeds: 3 readings, average 15.00 mph, max 20.00 mph")
+}
+
+// TestAnalyzeLogNoSpeeds tests the summary of a log without any sensor speeds
+func TestAnalyzeLogNoSpeeds(t *testing.T) {
+	summary, err := analyzeLog(strings.NewReader("2024/12/16 15:08:56 \x1b[31m[ERROR]\x1b[0m [BLE] scanning time limit reached\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, summary.reconnections())
+	assert.Contains(t, formatLogSummary(summary), "sensor speeds: none logged")
+}
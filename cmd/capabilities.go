@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os/exec"
+
+	ble "github.com/richbl/go-ble-sync-cycle/internal/ble"
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// externalBinaries lists the external binaries used by optional features: the MPV media player, and
+// common TTS commands for speed announcements
+var externalBinaries = []string{"mpv", "espeak", "espeak-ng", "spd-say", "say"}
+
+// capabilitiesReport describes the features supported by this build and the external binaries
+// available on this system
+type capabilitiesReport struct {
+	SpeedSources []string        `json:"speed_sources"`
+	SensorData   []string        `json:"sensor_data"`
+	DemoProfiles []string        `json:"demo_profiles"`
+	SpeedUnits   []string        `json:"speed_units"`
+	OnEnd        []string        `json:"on_end"`
+	Binaries     map[string]bool `json:"binaries"`
+}
+
+// printCapabilities prints the capabilities report as JSON
+func printCapabilities(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(buildCapabilities(exec.LookPath))
+}
+
+// buildCapabilities builds the capabilities report, using lookPath to find the external binaries
+func buildCapabilities(lookPath func(string) (string, error)) capabilitiesReport {
+	report := capabilitiesReport{
+		SpeedSources: config.SupportedSources,
+		SensorData:   ble.SupportedSensorData,
+		DemoProfiles: config.SupportedDemoProfiles,
+		SpeedUnits:   config.SupportedSpeedUnits,
+		OnEnd:        config.SupportedOnEnds,
+		Binaries:     make(map[string]bool, len(externalBinaries)),
+	}
+
+	for _, binary := range externalBinaries {
+		_, err := lookPath(binary)
+		report.Binaries[binary] = err == nil
+	}
+
+	return report
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ble "github.com/richbl/go-ble-sync-cycle/internal/ble"
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// TestBuildCapabilities tests that the capabilities report reflects the supported implementations
+// and the external binaries found
+func TestBuildCapabilities(t *testing.T) {
+	report := buildCapabilities(func(binary string) (string, error) {
+
+		if binary == "mpv" {
+			return "/usr/bin/mpv", nil
+		}
+
+		return "", errors.New("not found")
+	})
+
+	assert.Equal(t, config.SupportedSources, report.SpeedSources)
+	assert.Contains(t, report.SpeedSources, config.SourceDemo)
+	assert.Equal(t, ble.SupportedSensorData, report.SensorData)
+	assert.Equal(t, config.SupportedDemoProfiles, report.DemoProfiles)
+	assert.Equal(t, config.SupportedSpeedUnits, report.SpeedUnits)
+	assert.Equal(t, config.SupportedOnEnds, report.OnEnd)
+
+	assert.Len(t, report.Binaries, len(externalBinaries))
+	assert.True(t, report.Binaries["mpv"])
+	assert.False(t, report.Binaries["espeak"])
+}
+
+// TestPrintCapabilities tests that the capabilities report is printed as JSON
+func TestPrintCapabilities(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, printCapabilities(&out))
+
+	var report capabilitiesReport
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, config.SupportedSources, report.SpeedSources)
+	assert.Contains(t, report.Binaries, "mpv")
+}
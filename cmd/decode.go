@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	ble "github.com/richbl/go-ble-sync-cycle/internal/ble"
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// printDecodedFrames decodes CSC measurements written in hex with the configured sensor decoding,
+// and prints the decoded fields as JSON
+func printDecodedFrames(out io.Writer, hexFrames string, cfg config.Config) error {
+	frames, err := ble.DecodeFrames(hexFrames, cfg.BLE, cfg.Speed)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(frames)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// TestPrintDecodedFrames tests that decoded CSC measurements are printed as JSON
+func TestPrintDecodedFrames(t *testing.T) {
+	cfg := config.Config{Speed: config.SpeedConfig{WheelCircumferenceMM: 2000, SpeedUnits: config.SpeedUnitsKMH}}
+
+	var out bytes.Buffer
+	assert.NoError(t, printDecodedFrames(&out, "01 0A000000 0004,01 0C000000 0008", cfg))
+
+	var frames []map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &frames))
+	assert.Len(t, frames, 2)
+	assert.Equal(t, 12.0, frames[1]["wheel_revs"])
+	assert.InDelta(t, 14.4, frames[1]["speed"], 0.0001)
+	assert.NotContains(t, frames[1], "cadence_rpm")
+
+	assert.Error(t, printDecodedFrames(&out, "not hex", cfg))
+}
@@ -3,17 +3,23 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"os/signal"
+	"runtime"
+	"strconv"
 	"sync"
 	"syscall"
+	"time"
 
 	ble "github.com/richbl/go-ble-sync-cycle/internal/ble"
 	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
 	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
 	speed "github.com/richbl/go-ble-sync-cycle/internal/speed"
+	tts "github.com/richbl/go-ble-sync-cycle/internal/tts"
 	video "github.com/richbl/go-ble-sync-cycle/internal/video-player"
 
 	"tinygo.org/x/bluetooth"
@@ -24,19 +30,94 @@ type appControllers struct {
 	speedController *speed.SpeedController
 	videoPlayer     *video.PlaybackController
 	bleController   *ble.BLEController
+	demoSource      *ble.DemoSource
+	fallbackSource  *ble.DemoSource
+	scanAttempts    int
+	announcer       *tts.Announcer
+	shutdownOrder   string
+
+	calibrationWindow time.Duration
+}
+
+// componentErr holds the error type and component type used for logging
+type componentErr struct {
+	componentType logger.ComponentType
+	err           error
+}
+
+// component is a component controller run concurrently with the others until shutdown
+type component struct {
+	componentType logger.ComponentType
+	run           func(ctx context.Context) error
 }
 
 func main() {
+	// Parse command-line flags
+	configPath := flag.String("config", "config.toml", "path to the TOML configuration file, or a directory of TOML configuration fragments")
+	previewMode := flag.Bool("preview", false, "play the video at normal speed, ignoring the BLE sensor (no scan)")
+	analyzePath := flag.String("analyze", "", "print a diagnostic summary of a captured log file, then exit")
+	showCapabilities := flag.Bool("capabilities", false, "print the features supported by this build and the external binaries available (as JSON), then exit")
+	calibrateMode := flag.Bool("calibrate", false, "play the video at normal speed for a minute while riding at its pace, then log the matching speed_multiplier")
+	decodeFrames := flag.String("decode", "", "decode comma-separated CSC measurements written in hex with the configured sensor decoding, print the fields (as JSON), then exit")
+	flag.Parse()
+
+	// Report build capabilities instead of riding
+	if *showCapabilities {
+
+		if err := printCapabilities(os.Stdout); err != nil {
+			log.Fatal(logger.Magenta + "[FATAL]" + logger.Reset + " [APP] failed to report capabilities: " + err.Error())
+		}
+
+		return
+	}
+
+	// Analyze a captured log file instead of riding
+	if *analyzePath != "" {
+
+		if err := analyzeLogFile(*analyzePath, os.Stdout); err != nil {
+			log.Fatal(logger.Magenta + "[FATAL]" + logger.Reset + " [APP] failed to analyze log file: " + err.Error())
+		}
+
+		return
+	}
+
 	log.Println("Starting BLE Sync Cycle 0.6.2")
 
 	// Load configuration
-	cfg, err := config.LoadFile("config.toml")
+	cfg, err := config.LoadFile(*configPath)
 	if err != nil {
 		log.Fatal(logger.Magenta + "[FATAL]" + logger.Reset + " [APP] failed to load TOML configuration: " + err.Error())
 	}
 
+	// Decode sensor data instead of riding (logging only errors, to keep the JSON output clean)
+	if *decodeFrames != "" {
+		logger.Initialize("error")
+
+		if err := printDecodedFrames(os.Stdout, *decodeFrames, *cfg); err != nil {
+			log.Fatal(logger.Magenta + "[FATAL]" + logger.Reset + " [APP] failed to decode sensor data: " + err.Error())
+		}
+
+		return
+	}
+
 	// Initialize logger
 	logger.Initialize(cfg.App.LogLevel)
+	logger.EnableLifecycle(cfg.App.LogLifecycle)
+
+	// Warn about a wheel circumference that was likely entered in the wrong units
+	if warning := cfg.Speed.WheelCircumferenceWarning(); warning != "" {
+		logger.Warn(logger.SPEED, warning)
+	}
+
+	// Warn about video features that audio-only mode ignores
+	if warning := cfg.Video.AudioOnlyWarning(); warning != "" {
+		logger.Warn(logger.VIDEO, warning)
+	}
+
+	// Warn about a sensor UUID that a BLE scan would likely never find
+	if warning := cfg.BLE.SensorUUIDWarning(); warning != "" && cfg.BLE.Source == config.SourceBLE {
+		logger.Warn(logger.BLE, warning)
+	}
 
 	// Configure terminal output to prevent display of break (^C) character
 	restoreTerm := configureTerminal()
@@ -45,24 +126,117 @@ func main() {
 	// Ensure goodbye message is always output last
 	defer logger.Info(logger.APP, "BLE Sync Cycle 0.6.2 shutdown complete. Goodbye!")
 
-	// Create contexts for managing goroutines and cancellations
-	rootCtx, rootCancel := context.WithCancel(context.Background())
+	// Create contexts for managing goroutines and cancellations, limited to the maximum run time
+	rootCtx, rootCancel := withMaxRunTime(context.Background(), time.Duration(cfg.App.MaxRunSecs)*time.Second)
 	defer rootCancel()
 
 	// Create component controllers
-	controllers, componentType, err := setupAppControllers(*cfg)
+	controllers, componentType, err := setupAppControllers(*cfg, *previewMode)
 	if err != nil {
 		logger.Fatal(componentType, "failed to create controllers: "+err.Error())
 	}
 
+	if *calibrateMode {
+		controllers.calibrationWindow = calibrationWindow
+	}
+
 	// Create a WaitGroup to track goroutine lifetimes, and run the application controllers
 	var wg sync.WaitGroup
 
-	if componentType, err := startAppControllers(rootCtx, controllers, &wg); err != nil {
-		logger.Error(componentType, err.Error())
+	// Run only the video player in preview mode, otherwise run all application controllers
+	if *previewMode {
+
+		if err := startPreview(rootCtx, controllers); err != nil {
+			logComponentError(logger.VIDEO, err)
+		}
+
+	} else if componentType, err := startAppControllers(rootCtx, controllers, &wg); err != nil {
+		logComponentError(componentType, err)
 	}
 
 	wg.Wait() // Wait here for all goroutines to finish in main()... be patient
+	logger.Lifecycle(logger.APP, "goroutines running at shutdown: "+strconv.Itoa(runtime.NumGoroutine()))
+
+	if !*previewMode {
+		summary := controllers.speedController.GetRideSummary()
+		logRideSummary(summary, cfg.Speed)
+
+		if cfg.App.SummaryImagePath != "" {
+			saveSummaryImage(cfg.App.SummaryImagePath, summary, controllers.speedController.GetSpeedHistory(), cfg.Speed)
+		}
+
+	}
+
+}
+
+// calibrationWindow is how long the video plays at normal speed in calibration mode
+const calibrationWindow = time.Minute
+
+// errMaxRunTime is the reason for shutting down once the maximum run time is reached
+var errMaxRunTime = errors.New("maximum run time reached")
+
+// withMaxRunTime returns a copy of the context that is cancelled (with errMaxRunTime as its cause)
+// once the maximum run time has elapsed (0 = no limit)
+func withMaxRunTime(ctx context.Context, maxRunTime time.Duration) (context.Context, context.CancelFunc) {
+
+	if maxRunTime <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, maxRunTime, errMaxRunTime)
+
+	context.AfterFunc(ctx, func() {
+
+		if errors.Is(context.Cause(ctx), errMaxRunTime) {
+			logger.Info(logger.APP, "maximum run time of "+maxRunTime.String()+" reached, so shutting down...")
+		}
+
+	})
+
+	return ctx, cancel
+}
+
+// logComponentError logs the error that stopped a component, where reaching the end of the video
+// is a normal shutdown rather than an error
+func logComponentError(componentType logger.ComponentType, err error) {
+
+	if errors.Is(err, video.ErrVideoComplete) {
+		logger.Info(componentType, "video playback complete, so shutting down...")
+		return
+	}
+
+	if errors.Is(err, video.ErrCalibrated) {
+		logger.Info(componentType, "calibration complete, so shutting down...")
+		return
+	}
+
+	logger.Error(componentType, err.Error())
+}
+
+// logRideSummary logs the statistics of the completed ride in the configured speed units
+func logRideSummary(summary speed.RideSummary, speedConfig config.SpeedConfig) {
+	speedUnits := speedConfig.SpeedUnits
+	distance := speed.FormatDistance(summary.Distance, speedUnits, speedConfig.DistancePrecision)
+
+	logger.Info(logger.APP, fmt.Sprintf("ride summary: distance %s, moving time %s, elapsed time %s, average speed %.2f %s, max speed %.2f %s",
+		distance, summary.MovingTime.Round(time.Second), summary.ElapsedTime.Round(time.Second),
+		speed.ToSpeedUnits(summary.AverageSpeed, speedUnits), speedUnits, speed.ToSpeedUnits(summary.MaxSpeed, speedUnits), speedUnits))
+
+	if summary.Glitches > 0 {
+		logger.Warn(logger.APP, fmt.Sprintf("%d possible sensor glitches (sudden speed changes) during the ride", summary.Glitches))
+	}
+
+}
+
+// saveSummaryImage saves the ride summary card as a PNG file, logging the outcome
+func saveSummaryImage(path string, summary speed.RideSummary, history []float64, speedConfig config.SpeedConfig) {
+
+	if err := writeSummaryImage(path, summary, history, speedConfig); err != nil {
+		logger.Error(logger.APP, "failed to save ride summary image: "+err.Error())
+		return
+	}
+
+	logger.Info(logger.APP, "ride summary image saved to "+path)
 }
 
 // configureTerminal handles terminal char echo to prevent display of break (^C) character
@@ -81,106 +255,251 @@ func configureTerminal() func() {
 }
 
 // setupAppControllers creates and initializes the application controllers
-func setupAppControllers(cfg config.Config) (appControllers, logger.ComponentType, error) {
+func setupAppControllers(cfg config.Config, previewMode bool) (appControllers, logger.ComponentType, error) {
 	// Create speed  and video controllers
 	speedController := speed.NewSpeedController(cfg.Speed.SmoothingWindow)
+	speedController.SetLatencyCompensation(cfg.Speed.LatencyCompensation)
+	speedController.SetMaxChangeRate(speed.FromSpeedUnits(cfg.Speed.MaxSpeedChangeRate, cfg.Speed.SpeedUnits))
+	speedController.SetGapHold(time.Duration(cfg.Speed.HoldDuringGapSecs * float64(time.Second)))
+	speedController.SetMaxIntegrationStep(time.Duration(cfg.Speed.MaxIntegrationStepSecs * float64(time.Second)))
+	speedController.SetStatsWarmup(time.Duration(cfg.App.StatsWarmupSecs * float64(time.Second)))
+	speedController.SetHistoryMaxSamples(cfg.App.HistoryMaxSamples)
+	speedController.SetDisplaySmoothing(time.Duration(cfg.App.DisplaySmoothingSecs * float64(time.Second)))
+
+	// Confirm there's a display to show the video on (or fall back to audio-only playback)
+	if err := video.CheckDisplay(&cfg.Video); err != nil {
+		return appControllers{}, logger.VIDEO, err
+	}
+
 	videoPlayer, err := video.NewPlaybackController(cfg.Video, cfg.Speed)
 	if err != nil {
 		return appControllers{}, logger.VIDEO, errors.New("failed to create video player: " + err.Error())
 	}
 
-	// Create BLE controller
-	bleController, err := ble.NewBLEController(cfg.BLE, cfg.Speed)
-	if err != nil {
+	// Create optional cadence controller, smoothed independently of speed
+	var cadenceController *speed.CadenceController
+
+	if cfg.Cadence.Enabled {
+		cadenceController = speed.NewCadenceController(cfg.Cadence.SmoothingWindow)
+		videoPlayer.SetCadenceController(cadenceController)
+	}
+
+	// Skip the BLE controller (and BLE adapter) in preview mode
+	if previewMode {
+		return appControllers{
+			speedController: speedController,
+			videoPlayer:     videoPlayer,
+		}, logger.APP, nil
+	}
+
+	// Create BLE controller, or a simulated speed source in place of the BLE sensor
+	var bleController *ble.BLEController
+	var demoSource, fallbackSource *ble.DemoSource
+
+	if cfg.BLE.Source == config.SourceDemo {
+		demoSource = ble.NewDemoSource(cfg.BLE.Demo, cfg.Speed)
+	} else if bleController, err = ble.NewBLEController(cfg.BLE, cfg.Speed); err != nil {
 		return appControllers{}, logger.BLE, errors.New("failed to create BLE controller: " + err.Error())
+	} else {
+		bleController.SetCadenceController(cadenceController)
+	}
+
+	// Create the simulated speed source to fall back to if the BLE sensor can't be found
+	if bleController != nil && cfg.BLE.FallbackToSimAfter > 0 {
+		fallbackSource = ble.NewDemoSource(cfg.BLE.Demo, cfg.Speed)
+	}
+
+	// Create optional TTS speed announcer
+	var announcer *tts.Announcer
+
+	if cfg.TTS.Enabled {
+		announcer = tts.NewAnnouncer(cfg.TTS, cfg.Speed)
 	}
 
 	return appControllers{
 		speedController: speedController,
 		videoPlayer:     videoPlayer,
 		bleController:   bleController,
+		demoSource:      demoSource,
+		fallbackSource:  fallbackSource,
+		scanAttempts:    cfg.BLE.FallbackToSimAfter,
+		announcer:       announcer,
+		shutdownOrder:   cfg.App.ShutdownOrder,
 	}, logger.APP, nil
 }
 
 // startAppControllers is responsible for starting and managing the component controllers
 func startAppControllers(ctx context.Context, controllers appControllers, wg *sync.WaitGroup) (logger.ComponentType, error) {
-	// componentErr holds the error type and component type used for logging
-	type componentErr struct {
-		componentType logger.ComponentType
-		err           error
-	}
-
 	// Create shutdown signal
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	// Scan for BLE peripheral of interest
-	bleSpeedCharacter, err := scanForBLESpeedCharacteristic(ctx, controllers)
-	if err != nil {
+	// Scan for BLE peripheral of interest (not needed for a simulated speed source)
+	var bleSpeedCharacter *bluetooth.DeviceCharacteristic
 
-		// Check if the context was cancelled (user pressed Ctrl+C)
-		if ctx.Err() == context.Canceled {
-			return logger.APP, nil
+	if controllers.demoSource == nil {
+		var fallback bool
+		var err error
+
+		bleSpeedCharacter, fallback, err = scanWithFallback(ctx, controllers.scanAttempts, func(ctx context.Context) (*bluetooth.DeviceCharacteristic, error) {
+			return scanForBLESpeedCharacteristic(ctx, controllers)
+		})
+
+		if err != nil {
+
+			// Check if the context was cancelled (user pressed Ctrl+C, or the maximum run time was reached)
+			if ctx.Err() != nil {
+				return logger.APP, nil
+			}
+
+			return logger.BLE, errors.New("BLE peripheral scan failed: " + err.Error())
+		}
+
+		if fallback {
+			controllers.demoSource = controllers.fallbackSource
 		}
 
-		return logger.BLE, errors.New("BLE peripheral scan failed: " + err.Error())
 	}
 
-	// Start component controllers concurrently
-	errs := make(chan componentErr, 1)
+	// Announce speed (optional goroutine)
+	if controllers.announcer != nil {
+		wg.Add(1)
 
-	// Add two goroutines to the WaitGroup
-	wg.Add(2) // One for BLE monitoring, one for video playback
+		go func() {
+			defer wg.Done()
+			logger.Lifecycle(logger.TTS, "announcer goroutine started")
+			defer logger.Lifecycle(logger.TTS, "announcer goroutine stopped")
 
-	// Monitor BLE speed (goroutine)
-	go func() {
-		defer wg.Done()
+			controllers.announcer.Start(ctx, controllers.speedController)
+		}()
+
+	}
+
+	// Monitor BLE speed and play video concurrently, stopping them in the configured order
+	bleComponent := component{componentType: logger.BLE, run: func(ctx context.Context) error {
+		return monitorBLESpeed(ctx, controllers, bleSpeedCharacter)
+	}}
 
-		if err := monitorBLESpeed(ctx, controllers, bleSpeedCharacter); err != nil {
+	videoComponent := component{componentType: logger.VIDEO, run: func(ctx context.Context) error {
+		return playVideo(ctx, controllers)
+	}}
 
-			// Check if the context was cancelled (user pressed Ctrl+C)
-			if ctx.Err() == context.Canceled {
-				errs <- componentErr{logger.BLE, nil}
-				return
+	if controllers.shutdownOrder == config.ShutdownBLEFirst {
+		return runComponents(ctx, []component{bleComponent, videoComponent}, wg)
+	}
+
+	return runComponents(ctx, []component{videoComponent, bleComponent}, wg)
+}
+
+// runComponents runs the components concurrently until the context is cancelled or a component
+// fails, then tears them down in the order given, draining each before stopping the next
+func runComponents(ctx context.Context, components []component, wg *sync.WaitGroup) (logger.ComponentType, error) {
+	results := make([]componentErr, len(components))
+	cancels := make([]context.CancelCauseFunc, len(components))
+	done := make([]chan struct{}, len(components))
+	stopped := make(chan int, len(components))
+
+	// Detach the component contexts from the shutdown signal, so they're only cancelled in order
+	for i, c := range components {
+		var componentCtx context.Context
+		componentCtx, cancels[i] = context.WithCancelCause(context.WithoutCancel(ctx))
+		done[i] = make(chan struct{})
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			results[i] = runComponent(componentCtx, c.componentType, func() error {
+				return c.run(componentCtx)
+			})
+			close(done[i])
+			stopped <- i
+		}()
+
+	}
+
+	// Wait for the shutdown signal, a component failure, or all components to stop
+	failed := -1
+
+wait:
+	for remaining := len(components); remaining > 0; remaining-- {
+
+		select {
+		case <-ctx.Done():
+			break wait
+		case i := <-stopped:
+
+			if results[i].err != nil {
+				failed = i
+				break wait
 			}
 
-			errs <- componentErr{logger.BLE, err}
-			return
 		}
 
-		errs <- componentErr{logger.BLE, nil}
-	}()
+	}
 
-	// Play video (goroutine)
-	go func() {
-		defer wg.Done()
+	// Tear down the components in order
+	for i, c := range components {
+		logger.Lifecycle(c.componentType, "stopping component")
+		cancels[i](context.Cause(ctx))
+		<-done[i]
+	}
 
-		if err := playVideo(ctx, controllers); err != nil {
+	if failed >= 0 {
+		return results[failed].componentType, results[failed].err
+	}
 
-			// Check if the context was cancelled (user pressed Ctrl+C)
-			if ctx.Err() == context.Canceled {
-				errs <- componentErr{logger.VIDEO, nil}
-				return
-			}
+	return logger.APP, nil
+}
 
-			errs <- componentErr{logger.VIDEO, err}
-			return
+// runComponent runs a component function, converting a panic into a component error so the
+// application can shut down gracefully and report which component failed
+func runComponent(ctx context.Context, componentType logger.ComponentType, run func() error) (result componentErr) {
+	result.componentType = componentType
+	logger.Lifecycle(componentType, "component goroutine started")
+	defer logger.Lifecycle(componentType, "component goroutine stopped")
+
+	defer func() {
+
+		if r := recover(); r != nil {
+			logger.Error(componentType, fmt.Sprintf("recovered from panic: %v", r))
+			result.err = fmt.Errorf("component panicked: %v", r)
 		}
 
-		errs <- componentErr{logger.VIDEO, nil}
 	}()
 
-	// Wait for both component results
-	for i := 0; i < 2; i++ {
-		compErr := <-errs
+	if err := run(); err != nil {
 
-		if compErr.err != nil {
-			return compErr.componentType, compErr.err
+		// Check if the context was cancelled (user pressed Ctrl+C, or the maximum run time was reached)
+		if ctx.Err() != nil {
+			return result
 		}
 
+		result.err = err
 	}
 
-	return logger.APP, nil
+	return result
+}
+
+// startPreview plays the video at normal speed, bypassing the BLE and speed components
+func startPreview(ctx context.Context, controllers appControllers) error {
+	// Create shutdown signal
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info(logger.APP, "preview mode enabled, so ignoring the BLE sensor")
+
+	if err := controllers.videoPlayer.Preview(ctx); err != nil {
+
+		// Check if the context was cancelled (user pressed Ctrl+C, or the maximum run time was reached)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		return err
+	}
+
+	return nil
 }
 
 // scanForBLESpeedCharacteristic scans for the BLE CSC speed characteristic
@@ -205,6 +524,13 @@ func scanForBLESpeedCharacteristic(ctx context.Context, controllers appControlle
 	// Wait for the characteristic or an error
 	select {
 	case <-ctx.Done():
+
+		// Wait for the scan to stop, so the BLE adapter isn't left scanning
+		select {
+		case <-errChan:
+		case <-results:
+		}
+
 		return nil, ctx.Err()
 	case err := <-errChan:
 		return nil, err
@@ -214,12 +540,51 @@ func scanForBLESpeedCharacteristic(ctx context.Context, controllers appControlle
 
 }
 
-// monitorBLESpeed monitors the BLE speed characteristic
+// scanWithFallback runs the BLE scan up to the given number of attempts, and reports whether to fall
+// back to the simulated speed source once every attempt has failed (0 attempts = a single scan, with
+// no fallback)
+func scanWithFallback(ctx context.Context, attempts int, scan func(context.Context) (*bluetooth.DeviceCharacteristic, error)) (*bluetooth.DeviceCharacteristic, bool, error) {
+
+	if attempts <= 0 {
+		characteristic, err := scan(ctx)
+		return characteristic, false, err
+	}
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		characteristic, err := scan(ctx)
+		if err == nil {
+			return characteristic, false, nil
+		}
+
+		// Check if the context was cancelled (user pressed Ctrl+C)
+		if ctx.Err() != nil {
+			return nil, false, err
+		}
+
+		logger.Warn(logger.BLE, "BLE peripheral scan failed (attempt "+strconv.Itoa(attempt)+" of "+strconv.Itoa(attempts)+"): "+err.Error())
+	}
+
+	logger.Warn(logger.APP, "BLE sensor unavailable after "+strconv.Itoa(attempts)+" attempts, so falling back to simulated speeds")
+
+	return nil, true, nil
+}
+
+// monitorBLESpeed monitors the BLE speed characteristic (or the simulated speed source)
 func monitorBLESpeed(ctx context.Context, controllers appControllers, bleSpeedCharacter *bluetooth.DeviceCharacteristic) error {
+
+	if controllers.demoSource != nil {
+		return controllers.demoSource.Start(ctx, controllers.speedController)
+	}
+
 	return controllers.bleController.GetBLEUpdates(ctx, controllers.speedController, bleSpeedCharacter)
 }
 
-// playVideo starts the video player
+// playVideo starts the video player (at normal speed, when calibrating)
 func playVideo(ctx context.Context, controllers appControllers) error {
+
+	if controllers.calibrationWindow > 0 {
+		return controllers.videoPlayer.Calibrate(ctx, controllers.speedController, controllers.calibrationWindow)
+	}
+
 	return controllers.videoPlayer.Start(ctx, controllers.speedController)
 }
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
+
+	"tinygo.org/x/bluetooth"
+)
+
+// init initializes the logger for testing
+func init() {
+	logger.Initialize("debug")
+}
+
+// TestRunComponent tests that component errors and panics are reported with their component type
+func TestRunComponent(t *testing.T) {
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Define test cases
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		run     func() error
+		wantErr bool
+	}{
+		{
+			name: "component succeeds",
+			ctx:  context.Background(),
+			run:  func() error { return nil },
+		},
+		{
+			name:    "component fails",
+			ctx:     context.Background(),
+			run:     func() error { return errors.New("component failed") },
+			wantErr: true,
+		},
+		{
+			name: "component fails after cancellation",
+			ctx:  cancelledCtx,
+			run:  func() error { return errors.New("component failed") },
+		},
+		{
+			name:    "component panics",
+			ctx:     context.Background(),
+			run:     func() error { panic("fake component panic") },
+			wantErr: true,
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result componentErr
+
+			assert.NotPanics(t, func() {
+				result = runComponent(tt.ctx, logger.VIDEO, tt.run)
+			})
+
+			assert.Equal(t, logger.VIDEO, result.componentType)
+
+			if tt.wantErr {
+				assert.Error(t, result.err)
+				return
+			}
+
+			assert.NoError(t, result.err)
+		})
+	}
+
+}
+
+// TestRunComponentLifecycle tests that lifecycle log lines are emitted around a component run only
+// when lifecycle logging is enabled
+func TestRunComponentLifecycle(t *testing.T) {
+
+	for _, enabled := range []bool{true, false} {
+		output := captureLog(t, func() {
+			logger.EnableLifecycle(enabled)
+			defer logger.EnableLifecycle(false)
+
+			runComponent(context.Background(), logger.BLE, func() error {
+				logger.Info(logger.BLE, "simulated controller running")
+				return nil
+			})
+		})
+
+		started := strings.Index(output, "lifecycle: component goroutine started")
+		running := strings.Index(output, "simulated controller running")
+		stopped := strings.Index(output, "lifecycle: component goroutine stopped")
+
+		if !enabled {
+			assert.Equal(t, -1, started)
+			assert.Equal(t, -1, stopped)
+			continue
+		}
+
+		assert.True(t, started >= 0 && started < running && running < stopped, "lifecycle lines out of order:\n%s", output)
+	}
+
+}
+
+// captureLog returns the log output written while running fn
+func captureLog(t *testing.T, fn func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	assert.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	logger.Initialize("debug")
+
+	fn()
+
+	os.Stdout = stdout
+	logger.Initialize("debug")
+	assert.NoError(t, writer.Close())
+
+	output, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+
+	return string(output)
+}
+
+// TestScanWithFallback tests that repeated scan failures fall back to simulated speeds after the
+// configured number of attempts
+func TestScanWithFallback(t *testing.T) {
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Define test cases
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		attempts     int
+		failures     int
+		wantScans    int
+		wantFallback bool
+		wantErr      bool
+	}{
+		{
+			name:      "no fallback, scan succeeds",
+			ctx:       context.Background(),
+			wantScans: 1,
+		},
+		{
+			name:      "no fallback, scan fails",
+			ctx:       context.Background(),
+			failures:  1,
+			wantScans: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "scan succeeds after retries",
+			ctx:       context.Background(),
+			attempts:  3,
+			failures:  2,
+			wantScans: 3,
+		},
+		{
+			name:         "repeated failures fall back",
+			ctx:          context.Background(),
+			attempts:     3,
+			failures:     5,
+			wantScans:    3,
+			wantFallback: true,
+		},
+		{
+			name:      "cancelled scan doesn't fall back",
+			ctx:       cancelledCtx,
+			attempts:  3,
+			failures:  5,
+			wantScans: 1,
+			wantErr:   true,
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scans := 0
+
+			_, fallback, err := scanWithFallback(tt.ctx, tt.attempts, func(context.Context) (*bluetooth.DeviceCharacteristic, error) {
+				scans++
+
+				if scans <= tt.failures {
+					return nil, errors.New("fake scan failure")
+				}
+
+				return &bluetooth.DeviceCharacteristic{}, nil
+			})
+
+			assert.Equal(t, tt.wantScans, scans)
+			assert.Equal(t, tt.wantFallback, fallback)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+
+}
+
+// TestWithMaxRunTime tests that a run is shut down cleanly at the maximum run time, with the
+// maximum run time as the reason
+func TestWithMaxRunTime(t *testing.T) {
+	ctx, cancel := withMaxRunTime(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Run a long-running component until the context is done
+	start := time.Now()
+	result := runComponent(ctx, logger.VIDEO, func() error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	assert.NoError(t, result.err, "reaching the maximum run time should be a clean shutdown")
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, context.Cause(ctx), errMaxRunTime)
+
+	// Without a limit, only cancellation ends the run
+	ctx, cancel = withMaxRunTime(context.Background(), 0)
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline, "context should have no deadline without a maximum run time")
+
+	cancel()
+	assert.ErrorIs(t, context.Cause(ctx), context.Canceled)
+}
+
+// TestSetupAppControllersPreview tests that preview mode starts no BLE controller (or BLE adapter)
+func TestSetupAppControllersPreview(t *testing.T) {
+	cfg := config.Config{
+		Speed: config.SpeedConfig{SmoothingWindow: 5},
+		Video: config.VideoConfig{AudioOnly: true},
+	}
+
+	controllers, _, err := setupAppControllers(cfg, true)
+	assert.NoError(t, err)
+	assert.NotNil(t, controllers.videoPlayer)
+	assert.NotNil(t, controllers.speedController)
+	assert.Nil(t, controllers.bleController, "preview mode should start no BLE controller")
+	assert.Nil(t, controllers.demoSource)
+}
+
+// TestRunComponentsShutdownOrder tests that components are torn down in the order given, each
+// drained before the next is stopped
+func TestRunComponentsShutdownOrder(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	// fakeComponent blocks until stopped, then takes a while to drain
+	fakeComponent := func(componentType logger.ComponentType) component {
+		return component{componentType: componentType, run: func(ctx context.Context) error {
+			<-ctx.Done()
+			record(string(componentType) + " stopping")
+			time.Sleep(20 * time.Millisecond)
+			record(string(componentType) + " stopped")
+
+			return ctx.Err()
+		}}
+	}
+
+	// failingComponent fails once running, without waiting to be stopped
+	failingComponent := func(componentType logger.ComponentType) component {
+		return component{componentType: componentType, run: func(ctx context.Context) error {
+			time.Sleep(10 * time.Millisecond)
+			record(string(componentType) + " failed")
+
+			return errors.New("fake component failure")
+		}}
+	}
+
+	// Define test cases
+	tests := []struct {
+		name       string
+		components []component
+		cancel     bool
+		wantType   logger.ComponentType
+		wantErr    bool
+		wantEvents []string
+	}{
+		{
+			name:       "video first on shutdown signal",
+			components: []component{fakeComponent(logger.VIDEO), fakeComponent(logger.BLE)},
+			cancel:     true,
+			wantType:   logger.APP,
+			wantEvents: []string{"[VIDEO] stopping", "[VIDEO] stopped", "[BLE] stopping", "[BLE] stopped"},
+		},
+		{
+			name:       "BLE first on shutdown signal",
+			components: []component{fakeComponent(logger.BLE), fakeComponent(logger.VIDEO)},
+			cancel:     true,
+			wantType:   logger.APP,
+			wantEvents: []string{"[BLE] stopping", "[BLE] stopped", "[VIDEO] stopping", "[VIDEO] stopped"},
+		},
+		{
+			name:       "component failure",
+			components: []component{fakeComponent(logger.VIDEO), failingComponent(logger.BLE)},
+			wantType:   logger.BLE,
+			wantErr:    true,
+			wantEvents: []string{"[BLE] failed", "[VIDEO] stopping", "[VIDEO] stopped"},
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events = nil
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if tt.cancel {
+				time.AfterFunc(10*time.Millisecond, cancel)
+			}
+
+			var wg sync.WaitGroup
+			componentType, err := runComponents(ctx, tt.components, &wg)
+			wg.Wait()
+
+			assert.Equal(t, tt.wantType, componentType)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantEvents, events)
+		})
+	}
+
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+	speed "github.com/richbl/go-ble-sync-cycle/internal/speed"
+)
+
+// Ride summary card layout (pixels)
+const (
+	cardWidth      = 480
+	cardHeight     = 248
+	cardMargin     = 16
+	glyphScale     = 3
+	glyphWidth     = 5
+	glyphHeight    = 7
+	glyphAdvance   = (glyphWidth + 1) * glyphScale
+	lineAdvance    = (glyphHeight + 3) * glyphScale
+	sparklineTop   = 156
+	sparklineWidth = cardWidth - 2*cardMargin
+)
+
+// Ride summary card colors
+var (
+	cardBackground = color.RGBA{0x20, 0x22, 0x2A, 0xFF}
+	cardText       = color.RGBA{0xF0, 0xF0, 0xF0, 0xFF}
+	cardSparkline  = color.RGBA{0xFF, 0x9A, 0x2E, 0xFF}
+)
+
+// glyphs is a 5x7 bitmap font covering the characters of the ride summary card
+var glyphs = map[rune][glyphHeight]string{
+	'0': {" ### ", "#   #", "#  ##", "# # #", "##  #", "#   #", " ### "},
+	'1': {"  #  ", " ##  ", "  #  ", "  #  ", "  #  ", "  #  ", " ### "},
+	'2': {" ### ", "#   #", "    #", "   # ", "  #  ", " #   ", "#####"},
+	'3': {"#####", "   # ", "  #  ", "   # ", "    #", "#   #", " ### "},
+	'4': {"   # ", "  ## ", " # # ", "#  # ", "#####", "   # ", "   # "},
+	'5': {"#####", "#    ", "#### ", "    #", "    #", "#   #", " ### "},
+	'6': {"  ## ", " #   ", "#    ", "#### ", "#   #", "#   #", " ### "},
+	'7': {"#####", "    #", "   # ", "  #  ", " #   ", " #   ", " #   "},
+	'8': {" ### ", "#   #", "#   #", " ### ", "#   #", "#   #", " ### "},
+	'9': {" ### ", "#   #", "#   #", " ####", "    #", "   # ", " ##  "},
+	'.': {"     ", "     ", "     ", "     ", "     ", " ##  ", " ##  "},
+	':': {"     ", " ##  ", " ##  ", "     ", " ##  ", " ##  ", "     "},
+	'/': {"     ", "    #", "   # ", "  #  ", " #   ", "#    ", "     "},
+	'A': {" ### ", "#   #", "#   #", "#####", "#   #", "#   #", "#   #"},
+	'D': {"#### ", "#   #", "#   #", "#   #", "#   #", "#   #", "#### "},
+	'E': {"#####", "#    ", "#    ", "#### ", "#    ", "#    ", "#####"},
+	'G': {" ### ", "#   #", "#    ", "# ###", "#   #", "#   #", " ####"},
+	'H': {"#   #", "#   #", "#   #", "#####", "#   #", "#   #", "#   #"},
+	'I': {" ### ", "  #  ", "  #  ", "  #  ", "  #  ", "  #  ", " ### "},
+	'K': {"#   #", "#  # ", "# #  ", "##   ", "# #  ", "#  # ", "#   #"},
+	'M': {"#   #", "## ##", "# # #", "# # #", "#   #", "#   #", "#   #"},
+	'P': {"#### ", "#   #", "#   #", "#### ", "#    ", "#    ", "#    "},
+	'S': {" ####", "#    ", "#    ", " ### ", "    #", "    #", "#### "},
+	'T': {"#####", "  #  ", "  #  ", "  #  ", "  #  ", "  #  ", "  #  "},
+	'V': {"#   #", "#   #", "#   #", "#   #", "#   #", " # # ", "  #  "},
+	'X': {"#   #", "#   #", " # # ", "  #  ", " # # ", "#   #", "#   #"},
+}
+
+// writeSummaryImage renders the ride summary card and saves it as a PNG file
+func writeSummaryImage(path string, summary speed.RideSummary, history []float64, speedConfig config.SpeedConfig) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(file, renderSummaryImage(summary, history, speedConfig)); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// renderSummaryImage renders the ride summary card: distance, moving time, and average and max
+// speeds (in the configured speed units), over a sparkline of the speed history
+func renderSummaryImage(summary speed.RideSummary, history []float64, speedConfig config.SpeedConfig) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	draw.Draw(img, img.Bounds(), &image.Uniform{cardBackground}, image.Point{}, draw.Src)
+
+	speedUnits := speedConfig.SpeedUnits
+	lines := []string{
+		"DIST " + speed.FormatDistance(summary.Distance, speedUnits, speedConfig.DistancePrecision),
+		"TIME " + formatCardDuration(summary.MovingTime),
+		fmt.Sprintf("AVG %.1f %s", speed.ToSpeedUnits(summary.AverageSpeed, speedUnits), speedUnits),
+		fmt.Sprintf("MAX %.1f %s", speed.ToSpeedUnits(summary.MaxSpeed, speedUnits), speedUnits),
+	}
+
+	for i, line := range lines {
+		drawText(img, cardMargin, cardMargin+i*lineAdvance, strings.ToUpper(line), cardText)
+	}
+
+	drawSparkline(img, history, cardMargin, sparklineTop, sparklineWidth, cardHeight-cardMargin-sparklineTop, cardSparkline)
+
+	return img
+}
+
+// formatCardDuration formats a duration as hours, minutes and seconds (e.g., "1:05:09")
+func formatCardDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// drawText draws text in the bitmap font with its top left corner at x, y (characters missing
+// from the font are left blank)
+func drawText(img *image.RGBA, x, y int, text string, c color.RGBA) {
+
+	for i, r := range []rune(text) {
+		glyph, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+
+		for row, bits := range glyph {
+
+			for col, bit := range bits {
+
+				if bit == '#' {
+					rect := image.Rect(0, 0, glyphScale, glyphScale).Add(image.Pt(x+i*glyphAdvance+col*glyphScale, y+row*glyphScale))
+					draw.Draw(img, rect, &image.Uniform{c}, image.Point{}, draw.Src)
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+// drawSparkline draws the speed history as a line scaled to fill the given area, from zero speed
+// at the bottom to the highest speed at the top
+func drawSparkline(img *image.RGBA, history []float64, x, y, width, height int, c color.RGBA) {
+
+	if len(history) == 0 {
+		return
+	}
+
+	top := slices.Max(history)
+	if top <= 0 {
+		top = 1
+	}
+
+	// point returns the position of the i-th speed sample in the sparkline area
+	point := func(i int) image.Point {
+		px := x
+
+		if len(history) > 1 {
+			px += i * (width - 1) / (len(history) - 1)
+		}
+
+		return image.Pt(px, y+height-1-int(history[i]/top*float64(height-1)))
+	}
+
+	previous := point(0)
+
+	for i := range history {
+		next := point(i)
+		drawLine(img, previous, next, c)
+		previous = next
+	}
+
+}
+
+// drawLine draws a one pixel wide line between two points (Bresenham's line algorithm)
+func drawLine(img *image.RGBA, from, to image.Point, c color.RGBA) {
+	dx, dy := abs(to.X-from.X), -abs(to.Y-from.Y)
+	sx, sy := 1, 1
+
+	if from.X > to.X {
+		sx = -1
+	}
+
+	if from.Y > to.Y {
+		sy = -1
+	}
+
+	for err := dx + dy; ; {
+		img.SetRGBA(from.X, from.Y, c)
+
+		if from == to {
+			return
+		}
+
+		e2 := 2 * err
+
+		if e2 >= dy {
+			err += dy
+			from.X += sx
+		}
+
+		if e2 <= dx {
+			err += dx
+			from.Y += sy
+		}
+
+	}
+
+}
+
+// abs returns the absolute value of an integer
+func abs(n int) int {
+
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
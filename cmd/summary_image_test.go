@@ -0,0 +1,70 @@
+package main
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+	speed "github.com/richbl/go-ble-sync-cycle/internal/speed"
+)
+
+// TestWriteSummaryImage tests that a synthetic ride produces a valid PNG summary card
+func TestWriteSummaryImage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ride.png")
+	summary := speed.RideSummary{
+		Distance:     12345.0,
+		MovingTime:   45*time.Minute + 12*time.Second,
+		AverageSpeed: 6.5,
+		MaxSpeed:     11.2,
+	}
+	history := []float64{0.0, 3.0, 6.5, 11.2, 8.0, 5.5, 0.0}
+
+	assert.NoError(t, writeSummaryImage(path, summary, history, config.SpeedConfig{SpeedUnits: config.SpeedUnitsKMH}))
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open summary card: %v", err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("summary card should be a valid PNG: %v", err)
+	}
+
+	assert.Equal(t, cardWidth, img.Bounds().Dx())
+	assert.Equal(t, cardHeight, img.Bounds().Dy())
+
+	// Both the text and the sparkline should have been drawn over the background
+	var textPixels, sparklinePixels int
+
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+
+			switch img.At(x, y) {
+			case cardText:
+				textPixels++
+			case cardSparkline:
+				sparklinePixels++
+			}
+
+		}
+
+	}
+
+	assert.Positive(t, textPixels, "summary card has no text")
+	assert.Positive(t, sparklinePixels, "summary card has no sparkline")
+}
+
+// TestFormatCardDuration tests formatting of the moving time on the summary card
+func TestFormatCardDuration(t *testing.T) {
+	assert.Equal(t, "0:00:00", formatCardDuration(0))
+	assert.Equal(t, "0:45:12", formatCardDuration(45*time.Minute+12*time.Second))
+	assert.Equal(t, "1:05:09", formatCardDuration(time.Hour+5*time.Minute+9*time.Second))
+}
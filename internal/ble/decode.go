@@ -0,0 +1,108 @@
+package ble
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+	speed "github.com/richbl/go-ble-sync-cycle/internal/speed"
+)
+
+// DecodedFrame holds the fields decoded from a single CSC measurement, for protocol debugging.
+// Speed and cadence are those the sensor decoding reports for the frame, given the frames before it
+type DecodedFrame struct {
+	Hex            string   `json:"hex"`
+	Length         int      `json:"length"`
+	Flags          string   `json:"flags,omitempty"`
+	WheelData      bool     `json:"wheel_data"`
+	CrankData      bool     `json:"crank_data"`
+	WheelRevs      *uint32  `json:"wheel_revs,omitempty"`
+	WheelEventTime *uint16  `json:"wheel_event_time,omitempty"`
+	CrankRevs      *uint16  `json:"crank_revs,omitempty"`
+	CrankEventTime *uint16  `json:"crank_event_time,omitempty"`
+	Speed          *float64 `json:"speed,omitempty"`
+	SpeedUnits     string   `json:"speed_units,omitempty"`
+	Cadence        *float64 `json:"cadence_rpm,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// DecodeFrames decodes comma-separated CSC measurements written in hex (e.g., "01 0A000000 0004"),
+// using the sensor decoding quirks and wheel circumference of the given configuration
+func DecodeFrames(hexFrames string, bleConfig config.BLEConfig, speedConfig config.SpeedConfig) ([]DecodedFrame, error) {
+	m := &BLEController{bleConfig: bleConfig, speedConfig: speedConfig}
+
+	// Decode from a fresh state, leaving the live decoding state untouched
+	live := decoding
+	decoding = decodeState{}
+
+	defer func() { decoding = live }()
+
+	var frames []DecodedFrame
+
+	for _, hexFrame := range strings.Split(hexFrames, ",") {
+		data, err := parseHexFrame(hexFrame)
+		if err != nil {
+			return nil, err
+		}
+
+		frames = append(frames, m.decodeFrame(data))
+	}
+
+	return frames, nil
+}
+
+// parseHexFrame parses a CSC measurement written in hex, ignoring spaces, colons and dashes
+func parseHexFrame(hexFrame string) ([]byte, error) {
+	cleaned := strings.NewReplacer(" ", "", ":", "", "-", "").Replace(strings.TrimSpace(hexFrame))
+
+	if cleaned == "" {
+		return nil, errors.New("empty frame")
+	}
+
+	data, err := hex.DecodeString(cleaned)
+	if err != nil {
+		return nil, errors.New("invalid hex frame \"" + hexFrame + "\": " + err.Error())
+	}
+
+	return data, nil
+}
+
+// decodeFrame decodes the fields of a CSC measurement, and runs it through the sensor decoding
+// for the speed and cadence it reports
+func (m *BLEController) decodeFrame(data []byte) DecodedFrame {
+	quirks := m.bleConfig.Quirks
+	frame := DecodedFrame{
+		Hex:    strings.ToUpper(hex.EncodeToString(data)),
+		Length: len(data),
+	}
+
+	frame.Flags = fmt.Sprintf("0x%02X", data[0])
+	frame.WheelData = hasWheelData(data[0], quirks)
+	frame.CrankData = data[0]&crankRevFlag != 0
+
+	if minLength := minFrameLength(data, quirks); len(data) < minLength {
+		frame.Error = fmt.Sprintf("undersized frame (%d bytes, need %d)", len(data), minLength)
+	}
+
+	if measurement, err := m.parseSpeedData(data); err == nil {
+		frame.WheelRevs, frame.WheelEventTime = &measurement.wheelRevs, &measurement.wheelTime
+	}
+
+	if crankRevs, crankTime, ok := parseCrankData(data, quirks); ok {
+		frame.CrankRevs, frame.CrankEventTime = &crankRevs, &crankTime
+	}
+
+	// Capture the cadence the sensor decoding reports (if any) along with its speed
+	m.cadenceHook = func(cadence float64) {
+		frame.Cadence = &cadence
+	}
+
+	if sensorSpeed, _, ok := m.processBLEData(data); ok {
+		frameSpeed := speed.ToSpeedUnits(sensorSpeed, m.speedConfig.SpeedUnits)
+		frame.Speed, frame.SpeedUnits = &frameSpeed, m.speedConfig.SpeedUnits
+	}
+
+	return frame
+}
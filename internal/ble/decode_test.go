@@ -0,0 +1,77 @@
+package ble
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// TestDecodeFrames tests decoding known CSC measurements, with the speed and cadence the sensor
+// decoding reports given the previous frames
+func TestDecodeFrames(t *testing.T) {
+	speedConfig := config.SpeedConfig{WheelCircumferenceMM: 2000, SpeedUnits: config.SpeedUnitsKMH}
+
+	frames, err := DecodeFrames("03 0A000000 0004 0500 0004, 03:0C:00:00:00:00:08:06:00:00:08", config.BLEConfig{}, speedConfig)
+	assert.NoError(t, err)
+	assert.Len(t, frames, 2)
+
+	// The first frame only sets the baseline, so has no cadence and a zero speed
+	first := frames[0]
+	assert.Equal(t, "0x03", first.Flags)
+	assert.True(t, first.WheelData)
+	assert.True(t, first.CrankData)
+	assert.Equal(t, uint32(10), *first.WheelRevs)
+	assert.Equal(t, uint16(1024), *first.WheelEventTime)
+	assert.Equal(t, uint16(5), *first.CrankRevs)
+	assert.Equal(t, 0.0, *first.Speed)
+	assert.Nil(t, first.Cadence)
+
+	// 2 revolutions of a 2000mm wheel in 1s (1024/1024s), and 1 crank revolution in 1s
+	second := frames[1]
+	assert.Equal(t, "030C000000000806000008", second.Hex)
+	assert.InDelta(t, 14.4, *second.Speed, 0.0001)
+	assert.Equal(t, config.SpeedUnitsKMH, second.SpeedUnits)
+	assert.InDelta(t, 60.0, *second.Cadence, 0.0001)
+	assert.Empty(t, second.Error)
+
+	// A re-sent frame reports nothing new
+	frames, err = DecodeFrames("01 0A000000 0004, 01 0C000000 0008, 01 0C000000 0008", config.BLEConfig{}, speedConfig)
+	assert.NoError(t, err)
+	assert.InDelta(t, 14.4, *frames[1].Speed, 0.0001)
+	assert.Nil(t, frames[2].Speed)
+}
+
+// TestDecodeFramesCadenceToSpeed tests that crank-only frames decode to a speed derived from the
+// cadence, when a cadence to speed factor is configured
+func TestDecodeFramesCadenceToSpeed(t *testing.T) {
+	speedConfig := config.SpeedConfig{CadenceToSpeedFactor: 6.0, SpeedUnits: config.SpeedUnitsKMH}
+
+	frames, err := DecodeFrames("02 0500 0004, 02 0600 0008", config.BLEConfig{}, speedConfig)
+	assert.NoError(t, err)
+	assert.Len(t, frames, 2)
+
+	first := frames[0]
+	assert.False(t, first.WheelData)
+	assert.Nil(t, first.Speed)
+
+	// 1 crank revolution in 1s is 60 RPM, at 6m per revolution 6m/s (21.6 km/h)
+	second := frames[1]
+	assert.InDelta(t, 60.0, *second.Cadence, 0.0001)
+	assert.InDelta(t, 21.6, *second.Speed, 0.0001)
+	assert.Equal(t, config.SpeedUnitsKMH, second.SpeedUnits)
+}
+
+// TestDecodeFramesErrors tests decoding undersized and invalid frames
+func TestDecodeFramesErrors(t *testing.T) {
+	frames, err := DecodeFrames("01 0A00", config.BLEConfig{}, config.SpeedConfig{})
+	assert.NoError(t, err)
+	assert.Contains(t, frames[0].Error, "undersized frame (3 bytes, need 7)")
+
+	_, err = DecodeFrames("01 0G", config.BLEConfig{}, config.SpeedConfig{})
+	assert.Error(t, err)
+
+	_, err = DecodeFrames("01,", config.BLEConfig{}, config.SpeedConfig{})
+	assert.Error(t, err)
+}
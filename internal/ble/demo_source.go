@@ -0,0 +1,68 @@
+package ble
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
+	speed "github.com/richbl/go-ble-sync-cycle/internal/speed"
+)
+
+// demoUpdateInterval is the interval between simulated speed updates (similar to a BLE sensor)
+const demoUpdateInterval = time.Second
+
+// DemoSource represents a simulated speed source that replaces the BLE sensor (e.g., for demos)
+type DemoSource struct {
+	demoConfig  config.DemoConfig
+	speedConfig config.SpeedConfig
+}
+
+// NewDemoSource creates a new simulated speed source with the given configuration
+func NewDemoSource(demoConfig config.DemoConfig, speedConfig config.SpeedConfig) *DemoSource {
+	logger.Info(logger.BLE, "created new demo speed source ("+demoConfig.Profile+" profile)")
+
+	return &DemoSource{
+		demoConfig:  demoConfig,
+		speedConfig: speedConfig,
+	}
+}
+
+// Start sends simulated speeds to the speed controller until the context is cancelled
+func (d *DemoSource) Start(ctx context.Context, speedController *speed.SpeedController) error {
+	logger.Debug(logger.BLE, "starting simulated speed updates...")
+
+	ticker := time.NewTicker(demoUpdateInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			demoSpeed := d.SpeedAt(time.Since(start))
+			logger.Info(logger.SPEED, logger.Blue+"demo sensor speed: "+strconv.FormatFloat(demoSpeed, 'f', 2, 64)+" "+d.speedConfig.SpeedUnits)
+			speedController.UpdateSpeed(speed.FromSpeedUnits(demoSpeed, d.speedConfig.SpeedUnits))
+		}
+	}
+
+}
+
+// SpeedAt returns the simulated speed (in speed units) at the elapsed time into the repeating speed profile
+func (d *DemoSource) SpeedAt(elapsed time.Duration) float64 {
+	period := time.Duration(d.demoConfig.PeriodSecs) * time.Second
+	phase := float64(elapsed%period) / float64(period)
+	speedRange := d.demoConfig.MaxSpeed - d.demoConfig.MinSpeed
+
+	// Sawtooth ramps from min to max speed, then drops back to min speed
+	if d.demoConfig.Profile == config.DemoProfileSawtooth {
+		return d.demoConfig.MinSpeed + (speedRange * phase)
+	}
+
+	// Sine rises from min speed to max speed at half period, then falls back to min speed
+	return d.demoConfig.MinSpeed + (speedRange * (1 - math.Cos(2*math.Pi*phase)) / 2)
+}
@@ -0,0 +1,60 @@
+package ble
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// TestDemoSourceSpeedAt tests that the simulated speed profiles match their shape and bounds
+func TestDemoSourceSpeedAt(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name    string
+		profile string
+		elapsed []time.Duration
+		want    []float64
+	}{
+		{
+			name:    "sine profile",
+			profile: config.DemoProfileSine,
+			elapsed: []time.Duration{0, 15 * time.Second, 30 * time.Second, 45 * time.Second, 60 * time.Second},
+			want:    []float64{10.0, 15.0, 20.0, 15.0, 10.0},
+		},
+		{
+			name:    "sawtooth profile",
+			profile: config.DemoProfileSawtooth,
+			elapsed: []time.Duration{0, 15 * time.Second, 30 * time.Second, 45 * time.Second, 60 * time.Second},
+			want:    []float64{10.0, 12.5, 15.0, 17.5, 10.0},
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := NewDemoSource(config.DemoConfig{
+				Profile:    tt.profile,
+				MinSpeed:   10.0,
+				MaxSpeed:   20.0,
+				PeriodSecs: 60,
+			}, config.SpeedConfig{})
+
+			// Check speeds at known points in the profile
+			for i, elapsed := range tt.elapsed {
+				assert.InDelta(t, tt.want[i], source.SpeedAt(elapsed), 0.001, "speed mismatch at %v", elapsed)
+			}
+
+			// Check that speeds stay within bounds over one period
+			for elapsed := time.Duration(0); elapsed < time.Minute; elapsed += time.Second {
+				speed := source.SpeedAt(elapsed)
+				assert.GreaterOrEqual(t, speed, 10.0)
+				assert.LessOrEqual(t, speed, 20.0)
+			}
+
+		})
+	}
+
+}
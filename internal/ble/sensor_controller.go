@@ -5,7 +5,9 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"tinygo.org/x/bluetooth"
@@ -17,12 +19,55 @@ import (
 
 // Constants for speed calculations and BLE data parsing
 const (
-	minDataLength = 7
-	wheelRevFlag  = uint8(0x01)
-	kphConversion = 3.6
-	mphConversion = 2.23694
+	wheelRevFlag = uint8(0x01)
+
+	// Default offsets of the wheel revolution and wheel event time fields in a CSC measurement
+	wheelRevsOffset = 1
+	wheelTimeOffset = 5
+
+	crankRevFlag  = uint8(0x02)
+	crankDataSize = 4
+
+	// Millimeters per meter, for wheel circumferences
+	mmPerMeter = 1000.0
+
+	// Crank event times are in 1/1024ths of a second
+	crankTimeUnitsPerSecond = 1024
+
+	// Seconds per minute, for cadences in revolutions per minute
+	secondsPerMinute = 60.0
+
+	// Minimum CSC measurement lengths, by the sensor data its flags report: flags and wheel data,
+	// flags and crank data only, or flags alone
+	minWheelFrameLength = 7
+	minCrankFrameLength = 1 + crankDataSize
+	minFlagsFrameLength = 1
+
+	// Duplicate measurements received for at least this long are treated as a stop
+	duplicateStopTimeout = 3 * time.Second
+
+	// A partial CSC frame not completed within this time is discarded
+	frameReassemblyTimeout = time.Second
+
+	// Delay between service or characteristic discovery attempts
+	discoveryRetryDelay = 500 * time.Millisecond
+
+	// Delay between attempts to stop a cancelled scan, and the time allowed for it to stop
+	scanStopRetryDelay = 100 * time.Millisecond
+	scanStopTimeout    = 2 * time.Second
 )
 
+// Phases of the BLE startup sequence, bounded by the startup budget
+const (
+	phaseScan      = "scan"
+	phaseConnect   = "connect"
+	phaseDiscover  = "discover"
+	phaseSubscribe = "subscribe"
+)
+
+// SupportedSensorData lists the BLE sensor data decoded by this build
+var SupportedSensorData = []string{"CSC wheel speed", "CSC crank cadence"}
+
 // SpeedMeasurement represents the wheel revolution and time data from a BLE sensor
 type SpeedMeasurement struct {
 	wheelRevs uint32
@@ -31,16 +76,44 @@ type SpeedMeasurement struct {
 
 // BLEController represents the BLE central controller component
 type BLEController struct {
-	bleConfig   config.BLEConfig
-	speedConfig config.SpeedConfig
-	bleAdapter  bluetooth.Adapter
+	bleConfig         config.BLEConfig
+	speedConfig       config.SpeedConfig
+	bleAdapter        bluetooth.Adapter
+	startupDeadline   time.Time
+	cadenceController *speed.CadenceController
+	cadenceHook       func(cadence float64)
 }
 
-// Package-level variables for tracking speed measurements
-var (
-	lastWheelRevs uint32
-	lastWheelTime uint16
-)
+// knownServiceNames names common BLE services, to help diagnose a device that isn't a CSC sensor
+var knownServiceNames = map[uint16]string{
+	0x180A: "Device Information",
+	0x180D: "Heart Rate",
+	0x180F: "Battery",
+	0x1814: "Running Speed and Cadence",
+	0x1816: "Cycling Speed and Cadence",
+	0x1818: "Cycling Power",
+	0x1826: "Fitness Machine",
+}
+
+// decodeState holds the sensor decoding state carried between speed measurements
+type decodeState struct {
+	lastWheelRevs       uint32
+	lastWheelTime       uint16
+	lastMeasurementTime time.Time
+	pendingFrame        []byte
+	pendingFrameTime    time.Time
+	undersizedFrames    int
+
+	lastSensorSpeed float64
+	zeroHeld        bool
+
+	lastCrankRevs            uint16
+	lastCrankTime            uint16
+	lastCrankMeasurementTime time.Time
+}
+
+// Package-level state for tracking speed measurements
+var decoding decodeState
 
 // NewBLEController creates a new BLE central controller for accessing a BLE peripheral
 func NewBLEController(bleConfig config.BLEConfig, speedConfig config.SpeedConfig) (*BLEController, error) {
@@ -60,49 +133,170 @@ func NewBLEController(bleConfig config.BLEConfig, speedConfig config.SpeedConfig
 	}, nil
 }
 
+// SetCadenceController sets the cadence controller fed with crank data from the BLE peripheral
+// (nil = ignore crank data)
+func (m *BLEController) SetCadenceController(cadenceController *speed.CadenceController) {
+	m.cadenceController = cadenceController
+}
+
+// sensorLabelSuffix returns the sensor label to append to log lines identifying the sensor, or an
+// empty string if the label is just the sensor address
+func (m *BLEController) sensorLabelSuffix() string {
+
+	if m.bleConfig.SensorLabel == "" || m.bleConfig.SensorLabel == m.bleConfig.SensorUUID {
+		return ""
+	}
+
+	return " (" + m.bleConfig.SensorLabel + ")"
+}
+
 // ScanForBLEPeripheral scans for a BLE peripheral with the specified UUID
 func (m *BLEController) ScanForBLEPeripheral(ctx context.Context) (bluetooth.ScanResult, error) {
 	// Create context with timeout
 	scanCtx, cancel := context.WithTimeout(ctx, time.Duration(m.bleConfig.ScanTimeoutSecs)*time.Second)
 	defer cancel()
 
+	logger.Info(logger.BLE, "now scanning the ether for BLE peripheral UUID of "+m.bleConfig.SensorUUID+m.sensorLabelSuffix()+"...")
+
+	// Wait for device discovery or timeout
+	result, err := awaitScan(scanCtx, m.startScanning, m.bleAdapter.StopScan)
+	if err != nil {
+
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return bluetooth.ScanResult{}, errors.New("scanning time limit reached")
+		}
+
+		return bluetooth.ScanResult{}, err
+	}
+
+	logger.Debug(logger.BLE, "found BLE peripheral "+result.Address.String()+m.sensorLabelSuffix())
+
+	return result, nil
+}
+
+// awaitScan runs the scan function until it finds the target peripheral or fails, or the context
+// is done, in which case the scan is stopped and its goroutine joined (within scanStopTimeout)
+// before returning, so the BLE adapter is never left scanning
+func awaitScan(ctx context.Context, scan func(found chan<- bluetooth.ScanResult) error, stopScan func() error) (bluetooth.ScanResult, error) {
 	found := make(chan bluetooth.ScanResult, 1)
 	errChan := make(chan error, 1)
+	done := make(chan struct{})
 
 	go func() {
-		logger.Info(logger.BLE, "now scanning the ether for BLE peripheral UUID of "+m.bleConfig.SensorUUID+"...")
+		defer close(done)
+		logger.Lifecycle(logger.BLE, "adapter scan started")
+		defer logger.Lifecycle(logger.BLE, "adapter scan stopped")
 
-		if err := m.startScanning(found); err != nil {
+		if err := scan(found); err != nil {
 			errChan <- err
 		}
 
 	}()
 
-	// Wait for device discovery or timeout
 	select {
 	case result := <-found:
-		logger.Debug(logger.BLE, "found BLE peripheral "+result.Address.String())
 		return result, nil
 	case err := <-errChan:
 		return bluetooth.ScanResult{}, err
-	case <-scanCtx.Done():
+	case <-ctx.Done():
+		stopScanUntilDone(stopScan, done)
+
+		return bluetooth.ScanResult{}, ctx.Err()
+	}
+
+}
+
+// stopScanUntilDone stops the scan and waits for its goroutine to close done, retrying a failed
+// stop (a scan cancelled before it started cannot yet be stopped), and gives up after scanStopTimeout
+func stopScanUntilDone(stopScan func() error, done <-chan struct{}) {
+	retry := time.NewTicker(scanStopRetryDelay)
+	defer retry.Stop()
+
+	timeout := time.NewTimer(scanStopTimeout)
+	defer timeout.Stop()
+
+	err := stopScan()
+
+	for {
+
+		select {
+		case <-done:
+			return
+		case <-timeout.C:
+
+			if err != nil {
+				logger.Error(logger.BLE, "failed to stop scan: "+err.Error())
+			} else {
+				logger.Error(logger.BLE, "scan did not stop within "+scanStopTimeout.String())
+			}
+
+			return
+		case <-retry.C:
+
+			if err != nil {
+				err = stopScan()
+			}
 
-		if err := m.bleAdapter.StopScan(); err != nil {
-			logger.Error(logger.BLE, "failed to stop scan: "+err.Error())
 		}
 
-		return bluetooth.ScanResult{}, errors.New("scanning time limit reached")
 	}
 
 }
 
 // startScanning starts the BLE scan and sends the result to the found channel when the target device is discovered
 func (m *BLEController) startScanning(found chan<- bluetooth.ScanResult) error {
-	// Start BLE scan
-	err := m.bleAdapter.Scan(func(adapter *bluetooth.Adapter, result bluetooth.ScanResult) {
+	// Start BLE scan, recovering once from an adapter left scanning (e.g., by a previous crashed run)
+	err := scanWithRecovery(func() error {
+		return m.scan(found)
+	}, m.bleAdapter.StopScan)
+
+	if err != nil {
+		logger.Error(logger.BLE, "scan error: "+err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// scanWithRecovery runs the scan function and, if the adapter is already scanning, stops that
+// scan and retries the scan function once
+func scanWithRecovery(scan func() error, stopScan func() error) error {
+	err := scan()
+	if err == nil || !isAlreadyScanning(err) {
+		return err
+	}
+
+	logger.Warn(logger.BLE, "BLE adapter already scanning, so stopping that scan and retrying...")
+
+	if err := stopScan(); err != nil {
+		logger.Debug(logger.BLE, "failed to stop previous scan: "+err.Error())
+	}
+
+	return scan()
+}
+
+// isAlreadyScanning reports whether the error indicates that the BLE adapter is already scanning
+func isAlreadyScanning(err error) bool {
+	msg := strings.ToLower(err.Error())
+
+	return strings.Contains(msg, "already in progress") || strings.Contains(msg, "already scanning")
+}
+
+// scan scans for BLE peripherals and sends the result to the found channel when the target device is discovered
+func (m *BLEController) scan(found chan<- bluetooth.ScanResult) error {
+	serviceUUID, err := parseServiceUUID(m.bleConfig.ScanServiceUUID)
+	if err != nil {
+		return err
+	}
+
+	if serviceUUID != nil {
+		logger.Debug(logger.BLE, "filtering scan results by advertised service UUID "+serviceUUID.String())
+	}
+
+	return m.bleAdapter.Scan(func(adapter *bluetooth.Adapter, result bluetooth.ScanResult) {
 
 		// Check if the target peripheral was found
-		if result.Address.String() == m.bleConfig.SensorUUID {
+		if matchesScanFilter(result, m.bleConfig.SensorUUID, serviceUUID) {
 
 			// Stop scanning
 			if err := m.bleAdapter.StopScan(); err != nil {
@@ -114,45 +308,130 @@ func (m *BLEController) startScanning(found chan<- bluetooth.ScanResult) error {
 		}
 
 	})
+}
+
+// matchesScanFilter reports whether a scan result is the target peripheral, requiring that its
+// advertisement include the service UUID (if any)
+func matchesScanFilter(result bluetooth.ScanResult, sensorAddress string, serviceUUID *bluetooth.UUID) bool {
+
+	if serviceUUID != nil && (result.AdvertisementPayload == nil || !result.HasServiceUUID(*serviceUUID)) {
+		return false
+	}
+
+	return normalizeAddress(result.Address.String()) == normalizeAddress(sensorAddress)
+}
+
+// normalizeAddress normalizes a peripheral address for comparison, since the same address may be
+// written in either case, and with colons, dashes, or no separators
+func normalizeAddress(address string) string {
+	return strings.ToUpper(strings.NewReplacer(":", "", "-", "", " ", "").Replace(strings.TrimSpace(address)))
+}
+
+// parseServiceUUID parses a 16-bit (e.g., "1816") or 128-bit service UUID, returning nil if the
+// UUID is empty (no filtering)
+func parseServiceUUID(uuid string) (*bluetooth.UUID, error) {
+
+	if uuid == "" {
+		return nil, nil
+	}
+
+	// Expand a 16-bit UUID using the Bluetooth base UUID
+	if len(uuid) == 4 {
+		shortUUID, err := strconv.ParseUint(uuid, 16, 16)
+		if err != nil {
+			return nil, errors.New("invalid service UUID: " + uuid)
+		}
+
+		serviceUUID := bluetooth.New16BitUUID(uint16(shortUUID))
+
+		return &serviceUUID, nil
+	}
 
+	serviceUUID, err := bluetooth.ParseUUID(uuid)
 	if err != nil {
-		logger.Error(logger.BLE, "scan error: "+err.Error())
+		return nil, errors.New("invalid service UUID: " + uuid)
 	}
 
-	return nil
+	return &serviceUUID, nil
 }
 
 // GetBLECharacteristic scans for the BLE peripheral and returns CSC services/characteristics
 func (m *BLEController) GetBLECharacteristic(ctx context.Context, speedController *speed.SpeedController) (*bluetooth.DeviceCharacteristic, error) {
-	// Scan for BLE peripheral
+	// Start the startup budget, which bounds the scan through to the subscription in GetBLEUpdates
+	if m.bleConfig.StartupBudgetSecs > 0 {
+		m.startupDeadline = time.Now().Add(time.Duration(m.bleConfig.StartupBudgetSecs) * time.Second)
+	}
+
+	ctx, cancel := m.startupContext(ctx)
+	defer cancel()
+
+	// Scan for BLE peripheral (the scan itself honors the context, stopping before it returns)
 	result, err := m.ScanForBLEPeripheral(ctx)
 	if err != nil {
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, startupBudgetError(phaseScan)
+		}
+
 		return nil, err
 	}
 
-	logger.Debug(logger.BLE, "connecting to BLE peripheral device "+result.Address.String())
+	logger.Debug(logger.BLE, "connecting to BLE peripheral device "+result.Address.String()+m.sensorLabelSuffix())
 
 	// Connect to BLE peripheral device
-	var device bluetooth.Device
+	logger.Lifecycle(logger.BLE, "adapter connect started")
 
-	if device, err = m.bleAdapter.Connect(result.Address, bluetooth.ConnectionParams{}); err != nil {
+	device, err := runStartupPhase(ctx, phaseConnect, func() (bluetooth.Device, error) {
+		return m.bleAdapter.Connect(result.Address, bluetooth.ConnectionParams{})
+	}, func(device bluetooth.Device) {
+
+		// Disconnect a device connected after the startup budget expired
+		if err := device.Disconnect(); err != nil {
+			logger.Error(logger.BLE, "failed to disconnect late BLE peripheral connection: "+err.Error())
+		}
+
+		logger.Lifecycle(logger.BLE, "adapter late connection disconnected")
+	})
+	if err != nil {
+		logger.Lifecycle(logger.BLE, "adapter connect failed")
 		return nil, err
 	}
 
-	logger.Info(logger.BLE, "BLE peripheral device connected")
+	logger.Lifecycle(logger.BLE, "adapter connected")
+
+	logger.Info(logger.BLE, "BLE peripheral device connected"+m.sensorLabelSuffix())
+
+	// Discover CSC service and characteristic
+	return runStartupPhase(ctx, phaseDiscover, func() (*bluetooth.DeviceCharacteristic, error) {
+		return m.discoverCSCCharacteristic(ctx, device, result.Address.String())
+	}, nil)
+}
+
+// discoverCSCCharacteristic discovers the CSC service and measurement characteristic of a connected BLE peripheral
+func (m *BLEController) discoverCSCCharacteristic(ctx context.Context, device bluetooth.Device, address string) (*bluetooth.DeviceCharacteristic, error) {
 	logger.Debug(logger.BLE, "discovering CSC services "+bluetooth.New16BitUUID(0x1816).String())
 
-	// Find CSC service and characteristic
-	svc, err := device.DiscoverServices([]bluetooth.UUID{bluetooth.New16BitUUID(0x1816)})
+	// Find CSC service and characteristic (discovery can come up empty right after connecting)
+	svc, err := retryDiscovery(ctx, m.bleConfig.DiscoveryRetries, discoveryRetryDelay, func() ([]bluetooth.DeviceService, error) {
+		return device.DiscoverServices([]bluetooth.UUID{bluetooth.New16BitUUID(0x1816)})
+	})
 	if err != nil {
 		logger.Error(logger.BLE, "CSC services discovery failed: "+err.Error())
+
+		// List the services the device does expose, to help diagnose a wrong address or sensor type
+		if services, listErr := device.DiscoverServices(nil); listErr == nil {
+			return nil, missingCSCServiceError(address, serviceUUIDs(services))
+		}
+
 		return nil, err
 	}
 
 	logger.Debug(logger.BLE, "found CSC service "+svc[0].UUID().String())
 	logger.Debug(logger.BLE, "discovering CSC characteristics "+bluetooth.New16BitUUID(0x2A5B).String())
 
-	char, err := svc[0].DiscoverCharacteristics([]bluetooth.UUID{bluetooth.New16BitUUID(0x2A5B)})
+	char, err := retryDiscovery(ctx, m.bleConfig.DiscoveryRetries, discoveryRetryDelay, func() ([]bluetooth.DeviceCharacteristic, error) {
+		return svc[0].DiscoverCharacteristics([]bluetooth.UUID{bluetooth.New16BitUUID(0x2A5B)})
+	})
 	if err != nil {
 		logger.Warn(logger.BLE, "CSC characteristics discovery failed: "+err.Error())
 		return nil, err
@@ -162,19 +441,193 @@ func (m *BLEController) GetBLECharacteristic(ctx context.Context, speedControlle
 	return &char[0], nil
 }
 
+// serviceUUIDs returns the UUIDs of the discovered services
+func serviceUUIDs(services []bluetooth.DeviceService) []bluetooth.UUID {
+	uuids := make([]bluetooth.UUID, 0, len(services))
+
+	for _, service := range services {
+		uuids = append(uuids, service.UUID())
+	}
+
+	return uuids
+}
+
+// missingCSCServiceError returns a diagnostic error for a peripheral without the CSC service that
+// lists the services it does expose
+func missingCSCServiceError(address string, uuids []bluetooth.UUID) error {
+	names := make([]string, 0, len(uuids))
+
+	for _, uuid := range uuids {
+
+		if !uuid.Is16Bit() {
+			names = append(names, uuid.String())
+			continue
+		}
+
+		name := fmt.Sprintf("0x%04X", uuid.Get16Bit())
+
+		if known, ok := knownServiceNames[uuid.Get16Bit()]; ok {
+			name = known + " (" + name + ")"
+		}
+
+		names = append(names, name)
+	}
+
+	exposed := "no services"
+
+	if len(names) > 0 {
+		exposed = "only: " + strings.Join(names, ", ")
+	}
+
+	return errors.New("BLE peripheral " + address + " does not expose the Cycling Speed and Cadence service (0x1816), but exposes " +
+		exposed + "; the sensor_uuid may be wrong, or the device may be a different type of sensor")
+}
+
+// retryDiscovery runs the discovery function, retrying up to the given number of times (after a
+// delay) while discovery fails or finds nothing
+func retryDiscovery[T any](ctx context.Context, retries int, delay time.Duration, discover func() ([]T, error)) ([]T, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= retries; attempt++ {
+
+		if attempt > 0 {
+			logger.Debug(logger.BLE, "retrying discovery (attempt "+strconv.Itoa(attempt+1)+" of "+strconv.Itoa(retries+1)+")...")
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+
+		}
+
+		found, err := discover()
+		if err == nil && len(found) > 0 {
+			return found, nil
+		}
+
+		lastErr = err
+
+		if lastErr == nil {
+			lastErr = errors.New("no matching services or characteristics found")
+		}
+
+	}
+
+	return nil, lastErr
+}
+
+// startupContext returns a context bounded by the startup budget deadline (if any)
+func (m *BLEController) startupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+
+	if m.startupDeadline.IsZero() {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, m.startupDeadline)
+}
+
+// runStartupPhase runs a phase of the BLE startup sequence, returning an error naming the phase if
+// the startup budget expires before the phase completes, in which case a phase that later succeeds
+// is handed to the cleanup function (if any) to release what it acquired
+func runStartupPhase[T any](ctx context.Context, phase string, run func() (T, error), cleanup func(T)) (T, error) {
+	type phaseResult struct {
+		value T
+		err   error
+	}
+
+	done := make(chan phaseResult, 1)
+
+	go func() {
+		value, err := run()
+		done <- phaseResult{value, err}
+	}()
+
+	select {
+	case result := <-done:
+
+		if result.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return result.value, startupBudgetError(phase)
+		}
+
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero T
+
+		go func() {
+
+			if result := <-done; result.err == nil && cleanup != nil {
+				cleanup(result.value)
+			}
+
+		}()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return zero, startupBudgetError(phase)
+		}
+
+		return zero, ctx.Err()
+	}
+
+}
+
+// startupBudgetError returns the error for a startup budget that expired during the given phase
+func startupBudgetError(phase string) error {
+	return errors.New("BLE startup budget exceeded during " + phase + " phase")
+}
+
 // GetBLEUpdates enables BLE peripheral monitoring to report real-time sensor data
 func (m *BLEController) GetBLEUpdates(ctx context.Context, speedController *speed.SpeedController, char *bluetooth.DeviceCharacteristic) error {
 	logger.Debug(logger.BLE, "starting real-time monitoring of BLE sensor notifications...")
-	errChan := make(chan error, 1)
+	errChan := make(chan error, 2)
+
+	// Process each notification (notifications arrive on a BLE stack goroutine, so report panics
+	// here rather than crash)
+	handleNotification := func(buf []byte) {
+		defer func() {
+
+			if r := recover(); r != nil {
+
+				select {
+				case errChan <- fmt.Errorf("panic in BLE notification handler: %v", r):
+				default:
+				}
+
+			}
+
+		}()
+
+		if speed, revs, ok := m.processBLEData(buf); ok {
+			speedController.UpdateSpeed(speed)
+			speedController.UpdateWheelRevolutions(revs, m.speedConfig.WheelCircumferenceMM)
+		}
+
+	}
+
+	// Enable notifications with cleanup handling (subscribing is the last phase bounded by the
+	// startup budget)
+	subscribeCtx, cancelSubscribe := m.startupContext(ctx)
+
+	_, err := runStartupPhase(subscribeCtx, phaseSubscribe, func() (struct{}, error) {
+		return struct{}{}, char.EnableNotifications(handleNotification)
+	}, func(struct{}) {
+
+		// Unsubscribe notifications enabled after the startup budget expired
+		if err := char.EnableNotifications(nil); err != nil {
+			logger.Error(logger.BLE, "failed to disable late notifications: "+err.Error())
+		}
+
+		logger.Lifecycle(logger.BLE, "adapter late notifications disabled")
+	})
 
-	// Enable notifications with cleanup handling
-	if err := char.EnableNotifications(func(buf []byte) {
-		speed := m.ProcessBLESpeed(buf)
-		speedController.UpdateSpeed(speed)
-	}); err != nil {
+	cancelSubscribe()
+
+	if err != nil {
 		return err
 	}
 
+	logger.Lifecycle(logger.BLE, "adapter notifications enabled")
+
 	// Ensure notifications are disabled on exit
 	defer func() {
 
@@ -182,6 +635,7 @@ func (m *BLEController) GetBLEUpdates(ctx context.Context, speedController *spee
 			logger.Error(logger.BLE, "failed to disable notifications: "+err.Error())
 		}
 
+		logger.Lifecycle(logger.BLE, "adapter notifications disabled")
 	}()
 
 	// Handle context cancellation in separate goroutine
@@ -193,54 +647,343 @@ func (m *BLEController) GetBLEUpdates(ctx context.Context, speedController *spee
 	return <-errChan
 }
 
-// ProcessBLESpeed processes the raw speed data from the BLE peripheral
-func (m *BLEController) ProcessBLESpeed(data []byte) float64 {
+// ProcessBLESpeed processes the raw speed data from the BLE peripheral, returning the speed in
+// meters per second, or false if the data is a duplicate measurement that should be ignored
+func (m *BLEController) ProcessBLESpeed(data []byte) (float64, bool) {
+	speed, _, ok := m.processBLEData(data)
+
+	return speed, ok
+}
+
+// processBLEData processes the raw speed data from the BLE peripheral, returning the speed (in
+// meters per second) and the wheel revolutions since the last measurement, or false if the data is a duplicate measurement
+func (m *BLEController) processBLEData(data []byte) (float64, uint32, bool) {
+	// Wait for the rest of a CSC frame fragmented across notifications
+	data, complete := reassembleFrame(data, m.bleConfig.Quirks)
+	if !complete {
+		return 0.0, 0, false
+	}
+
+	// Reject a frame too short for the sensor data it reports, rather than reporting a zero speed
+	if minLength := minFrameLength(data, m.bleConfig.Quirks); len(data) < minLength {
+		countUndersizedFrame(len(data), minLength)
+		return 0.0, 0, false
+	}
+
+	// Feed any crank data to the (independently smoothed) cadence
+	cadence, newCadence := m.processBLECadence(data)
+
+	if newCadence && m.cadenceController != nil {
+		m.cadenceController.UpdateCadence(cadence)
+	}
+
+	if newCadence && m.cadenceHook != nil {
+		m.cadenceHook(cadence)
+	}
+
+	// Drive the speed from the cadence of a cadence-only sensor (no wheel data), if configured
+	if m.speedConfig.CadenceToSpeedFactor > 0 && !hasWheelData(data[0], m.bleConfig.Quirks) {
+
+		if !newCadence {
+			return 0.0, 0, false
+		}
+
+		sensorSpeed, ok := m.reportSensorSpeed(m.cadenceSpeed(cadence))
+
+		return sensorSpeed, 0, ok
+	}
+
+	// Otherwise a crank-only frame (its cadence consumed above) carries no speed, so leave the speed alone
+	if !hasWheelData(data[0], m.bleConfig.Quirks) && data[0]&crankRevFlag != 0 {
+		return 0.0, 0, false
+	}
+
 	// Parse speed data
 	newSpeedData, err := m.parseSpeedData(data)
 	if err != nil {
 		logger.Error(logger.SPEED, "invalid BLE data: "+err.Error())
-		return 0.0
+		return 0.0, 0, true
 	}
 
 	// Calculate speed from parsed data
-	speed := m.calculateSpeed(newSpeedData)
-	logger.Info(logger.SPEED, logger.Blue+"BLE sensor speed: "+strconv.FormatFloat(speed, 'f', 2, 64)+" "+m.speedConfig.SpeedUnits)
+	sensorSpeed, revs, ok := m.calculateSpeed(newSpeedData)
+	if !ok {
+		return 0.0, 0, false
+	}
+
+	if sensorSpeed, ok = m.reportSensorSpeed(sensorSpeed); !ok {
+		return 0.0, 0, false
+	}
+
+	return sensorSpeed, revs, true
+}
+
+// reportSensorSpeed applies the ambiguous zero policy to a calculated sensor speed and logs it,
+// returning false for a non-finite speed (from corrupt data) that should be ignored
+func (m *BLEController) reportSensorSpeed(sensorSpeed float64) (float64, bool) {
+
+	if math.IsNaN(sensorSpeed) || math.IsInf(sensorSpeed, 0) {
+		logger.Warn(logger.SPEED, "ignoring non-finite BLE sensor speed")
+		return 0.0, false
+	}
+
+	sensorSpeed = m.resolveAmbiguousZero(sensorSpeed)
+
+	displaySpeed := speed.ToSpeedUnits(sensorSpeed, m.speedConfig.SpeedUnits)
+	logger.Info(logger.SPEED, logger.Blue+"BLE sensor speed: "+strconv.FormatFloat(displaySpeed, 'f', 2, 64)+" "+m.speedConfig.SpeedUnits)
+
+	return sensorSpeed, true
+}
+
+// cadenceSpeed converts a cadence (in crank revolutions per minute) to a pseudo-speed in meters
+// per second, using the configured distance traveled per crank revolution
+func (m *BLEController) cadenceSpeed(cadence float64) float64 {
+	return cadence * m.speedConfig.CadenceToSpeedFactor / secondsPerMinute
+}
+
+// resolveAmbiguousZero applies the ambiguous zero policy to a zero speed arriving right after a
+// nonzero speed (which may be a sensor momentarily reporting no wheel movement between valid frames),
+// holding the last speed for a single frame under the "hold" policy
+func (m *BLEController) resolveAmbiguousZero(sensorSpeed float64) float64 {
+
+	if sensorSpeed == 0 && decoding.lastSensorSpeed > 0 && !decoding.zeroHeld && m.speedConfig.AmbiguousZeroPolicy == config.AmbiguousZeroHold {
+		logger.Debug(logger.SPEED, "holding last BLE sensor speed over an ambiguous zero speed")
+		decoding.zeroHeld = true
+
+		return decoding.lastSensorSpeed
+	}
 
-	return speed
+	decoding.zeroHeld = false
+	decoding.lastSensorSpeed = sensorSpeed
+
+	return sensorSpeed
 }
 
-// calculateSpeed calculates the current speed based on the sensor data
-func (m *BLEController) calculateSpeed(sm SpeedMeasurement) float64 {
+// calculateSpeed calculates the current speed and the wheel revolutions since the last measurement
+// based on the sensor data, returning false for duplicate measurements and sensor resets
+func (m *BLEController) calculateSpeed(sm SpeedMeasurement) (float64, uint32, bool) {
 	// First time through the loop set the last wheel revs and time
-	if lastWheelTime == 0 {
-		lastWheelRevs = sm.wheelRevs
-		lastWheelTime = sm.wheelTime
-		return 0.0
+	if decoding.lastWheelTime == 0 {
+		decoding.lastWheelRevs = sm.wheelRevs
+		decoding.lastWheelTime = sm.wheelTime
+		decoding.lastMeasurementTime = time.Now()
+		return 0.0, 0, true
+	}
+
+	// Ignore a re-sent measurement (same wheel revs and event time), unless measurements have
+	// been re-sent long enough that the wheel has likely stopped
+	if sm.wheelRevs == decoding.lastWheelRevs && sm.wheelTime == decoding.lastWheelTime {
+		return 0.0, 0, time.Since(decoding.lastMeasurementTime) >= duplicateStopTimeout
 	}
 
-	// Calculate delta between time intervals
-	timeDiff := sm.wheelTime - lastWheelTime
+	// Calculate delta between time intervals (likewise modulo 2^16 for the wheel event time)
+	timeDiff := sm.wheelTime - decoding.lastWheelTime
 
 	if timeDiff == 0 {
-		return 0.0
+		return 0.0, 0, true
+	}
+
+	// Calculate delta between wheel revs: unsigned subtraction is modulo 2^32, so a cumulative
+	// wheel revolution count that wraps past 0xFFFFFFFF still yields the correct (small) delta
+	revDiff := int32(sm.wheelRevs - decoding.lastWheelRevs)
+	decoding.lastWheelRevs = sm.wheelRevs
+	decoding.lastWheelTime = sm.wheelTime
+	decoding.lastMeasurementTime = time.Now()
+
+	// A sensor reset (wheel revs counting backwards) becomes the new baseline, rather than being
+	// reported as a negative speed
+	if revDiff < 0 {
+		logger.Debug(logger.SPEED, "BLE sensor wheel revolutions went backwards (sensor reset?), so resetting baseline")
+		return 0.0, 0, false
+	}
+
+	return m.wheelSpeed(uint32(revDiff), timeDiff), uint32(revDiff), true
+}
+
+// wheelSpeed calculates the speed in meters per second from wheel revolutions over a wheel event
+// time delta (in units of the wheel event time resolution, 1/1024ths of a second by default)
+func (m *BLEController) wheelSpeed(revDiff uint32, timeDiff uint16) float64 {
+	resolution := m.bleConfig.Quirks.WheelTimeResolution
+
+	if resolution <= 0 {
+		resolution = config.DefaultWheelTimeResolution
+	}
+
+	meters := float64(revDiff) * float64(m.speedConfig.WheelCircumferenceMM) / mmPerMeter
+	seconds := float64(timeDiff) / float64(resolution)
+
+	return meters / seconds
+}
+
+// processBLECadence calculates the cadence (in crank revolutions per minute) from the crank data
+// of a CSC measurement, returning false if the measurement has no new crank data
+func (m *BLEController) processBLECadence(data []byte) (float64, bool) {
+
+	crankRevs, crankTime, ok := parseCrankData(data, m.bleConfig.Quirks)
+	if !ok {
+		return 0.0, false
+	}
+
+	// First time through set the last crank revs and time
+	if decoding.lastCrankTime == 0 {
+		decoding.lastCrankRevs = crankRevs
+		decoding.lastCrankTime = crankTime
+		decoding.lastCrankMeasurementTime = time.Now()
+
+		return 0.0, false
+	}
+
+	// A re-sent measurement means no new crank revolution, so once measurements have been re-sent
+	// long enough, pedaling has likely stopped
+	if crankRevs == decoding.lastCrankRevs && crankTime == decoding.lastCrankTime {
+		return 0.0, time.Since(decoding.lastCrankMeasurementTime) >= duplicateStopTimeout
+	}
+
+	// Deltas are modulo 2^16, so counters that wrap still yield the correct (small) delta
+	revDiff := crankRevs - decoding.lastCrankRevs
+	timeDiff := crankTime - decoding.lastCrankTime
+	decoding.lastCrankRevs = crankRevs
+	decoding.lastCrankTime = crankTime
+	decoding.lastCrankMeasurementTime = time.Now()
+
+	if timeDiff == 0 {
+		return 0.0, false
+	}
+
+	return crankCadence(revDiff, timeDiff), true
+}
+
+// parseCrankData parses the cumulative crank revolutions and crank event time of a CSC
+// measurement, returning false if the measurement has no crank data
+func parseCrankData(data []byte, quirks config.QuirksConfig) (uint16, uint16, bool) {
+
+	if len(data) < 1 || data[0]&crankRevFlag == 0 {
+		return 0, 0, false
+	}
+
+	// Crank data follows the wheel data (if any)
+	offset := 1
+
+	if hasWheelData(data[0], quirks) {
+		offset = wheelDataLength(quirks)
+	}
+
+	if len(data) < offset+crankDataSize {
+		return 0, 0, false
+	}
+
+	return binary.LittleEndian.Uint16(data[offset:]), binary.LittleEndian.Uint16(data[offset+2:]), true
+}
+
+// crankCadence calculates the cadence in crank revolutions per minute from crank revolutions over
+// a crank event time delta (in 1/1024ths of a second)
+func crankCadence(revDiff, timeDiff uint16) float64 {
+	return float64(revDiff) * secondsPerMinute * crankTimeUnitsPerSecond / float64(timeDiff)
+}
+
+// reassembleFrame buffers a CSC frame split across consecutive notifications, returning the
+// complete frame once the length expected from its flags is present, or false while waiting
+func reassembleFrame(data []byte, quirks config.QuirksConfig) ([]byte, bool) {
+	frame := data
+
+	// Join a recent partial frame with this notification
+	if len(decoding.pendingFrame) > 0 {
+
+		if time.Since(decoding.pendingFrameTime) < frameReassemblyTimeout {
+			frame = append(decoding.pendingFrame, data...)
+		} else {
+			countUndersizedFrame(len(decoding.pendingFrame), expectedFrameLength(decoding.pendingFrame[0], quirks))
+		}
+
+		decoding.pendingFrame = nil
+	}
+
+	// Frames without wheel data can't be completed, so leave them to be checked as they are
+	if len(frame) < 1 || !hasWheelData(frame[0], quirks) {
+		return frame, true
+	}
+
+	expected := expectedFrameLength(frame[0], quirks)
+
+	// A joined frame longer than expected means the partial frame was garbage, so decode this
+	// notification on its own instead
+	if len(frame) > expected && len(frame) != len(data) {
+		countUndersizedFrame(len(frame)-len(data), expected)
+		return reassembleFrame(data, quirks)
 	}
 
-	// Calculate delta between wheel revs
-	revDiff := int32(sm.wheelRevs - lastWheelRevs)
+	// Buffer a short frame until the rest arrives (bounded by the expected frame length)
+	if len(frame) < expected {
+		decoding.pendingFrame = append([]byte(nil), frame...)
+		decoding.pendingFrameTime = time.Now()
 
-	// Determine speed unit conversion multiplier
-	speedConversion := kphConversion
+		return nil, false
+	}
 
-	if m.speedConfig.SpeedUnits == config.SpeedUnitsMPH {
-		speedConversion = mphConversion
+	return frame, true
+}
+
+// minFrameLength returns the minimum valid length of a CSC measurement, per the sensor data its
+// flags (or quirks) report
+func minFrameLength(data []byte, quirks config.QuirksConfig) int {
+
+	switch {
+	case len(data) == 0:
+		return minFlagsFrameLength
+	case hasWheelData(data[0], quirks):
+		return wheelDataLength(quirks)
+	case data[0]&crankRevFlag != 0:
+		return minCrankFrameLength
+	default:
+		return minFlagsFrameLength
 	}
 
-	// Calculate new speed
-	speed := float64(revDiff) * float64(m.speedConfig.WheelCircumferenceMM) * speedConversion / float64(timeDiff)
-	lastWheelRevs = sm.wheelRevs
-	lastWheelTime = sm.wheelTime
+}
 
-	return speed
+// countUndersizedFrame counts and logs a discarded CSC measurement that was too short
+func countUndersizedFrame(length int, minLength int) {
+	decoding.undersizedFrames++
+	logger.Warn(logger.BLE, fmt.Sprintf("discarding undersized CSC frame (%d bytes, need %d): %d undersized frames so far",
+		length, minLength, decoding.undersizedFrames))
+}
+
+// hasWheelData reports whether a CSC measurement includes wheel data, per its flags (or quirks)
+func hasWheelData(flags uint8, quirks config.QuirksConfig) bool {
+	return flags&wheelRevFlag != 0 || quirks.ForceWheelData
+}
+
+// wheelDataOffsets returns the offsets of the wheel revolution and wheel event time fields,
+// applying any offset quirks
+func wheelDataOffsets(quirks config.QuirksConfig) (int, int) {
+	revsOffset, timeOffset := wheelRevsOffset, wheelTimeOffset
+
+	if quirks.WheelRevsOffset > 0 {
+		revsOffset = quirks.WheelRevsOffset
+	}
+
+	if quirks.WheelTimeOffset > 0 {
+		timeOffset = quirks.WheelTimeOffset
+	}
+
+	return revsOffset, timeOffset
+}
+
+// wheelDataLength returns the minimum CSC measurement length that includes the wheel data fields
+func wheelDataLength(quirks config.QuirksConfig) int {
+	revsOffset, timeOffset := wheelDataOffsets(quirks)
+
+	return max(minWheelFrameLength, revsOffset+4, timeOffset+2)
+}
+
+// expectedFrameLength returns the CSC measurement length implied by its flags (and quirks)
+func expectedFrameLength(flags uint8, quirks config.QuirksConfig) int {
+	length := wheelDataLength(quirks)
+
+	if flags&crankRevFlag != 0 {
+		length += crankDataSize
+	}
+
+	return length
 }
 
 // parseSpeedData parses the raw speed data from the BLE peripheral
@@ -250,14 +993,18 @@ func (m *BLEController) parseSpeedData(data []byte) (SpeedMeasurement, error) {
 		return SpeedMeasurement{}, errors.New("empty data")
 	}
 
+	quirks := m.bleConfig.Quirks
+
 	// Validate data
-	if data[0]&wheelRevFlag == 0 || len(data) < minDataLength {
+	if !hasWheelData(data[0], quirks) || len(data) < wheelDataLength(quirks) {
 		return SpeedMeasurement{}, errors.New("invalid data format or length")
 	}
 
+	revsOffset, timeOffset := wheelDataOffsets(quirks)
+
 	// Return new speed data
 	return SpeedMeasurement{
-		wheelRevs: binary.LittleEndian.Uint32(data[1:]),
-		wheelTime: binary.LittleEndian.Uint16(data[5:]),
+		wheelRevs: binary.LittleEndian.Uint32(data[revsOffset:]),
+		wheelTime: binary.LittleEndian.Uint16(data[timeOffset:]),
 	}, nil
 }
@@ -0,0 +1,1156 @@
+package ble
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"tinygo.org/x/bluetooth"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
+	speed "github.com/richbl/go-ble-sync-cycle/internal/speed"
+)
+
+// init initializes the logger for testing
+func init() {
+	logger.Initialize("debug")
+}
+
+// TestScanWithRecovery tests recovery from a BLE adapter that is already scanning
+func TestScanWithRecovery(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name      string
+		scanErrs  []error
+		wantErr   bool
+		wantScans int
+		wantStops int
+	}{
+		{
+			name:      "scan succeeds",
+			scanErrs:  []error{nil},
+			wantScans: 1,
+		},
+		{
+			name:      "already scanning then scan succeeds",
+			scanErrs:  []error{errors.New("bluetooth: a scan is already in progress"), nil},
+			wantScans: 2,
+			wantStops: 1,
+		},
+		{
+			name:      "already scanning twice",
+			scanErrs:  []error{errors.New("Operation already in progress"), errors.New("Operation already in progress")},
+			wantErr:   true,
+			wantScans: 2,
+			wantStops: 1,
+		},
+		{
+			name:      "other scan error",
+			scanErrs:  []error{errors.New("adapter not powered")},
+			wantErr:   true,
+			wantScans: 1,
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scans, stops int
+
+			err := scanWithRecovery(func() error {
+				scans++
+				return tt.scanErrs[scans-1]
+			}, func() error {
+				stops++
+				return nil
+			})
+
+			assert.Equal(t, tt.wantErr, err != nil, "unexpected scan error: %v", err)
+			assert.Equal(t, tt.wantScans, scans, "scan count mismatch")
+			assert.Equal(t, tt.wantStops, stops, "stop scan count mismatch")
+		})
+	}
+
+}
+
+// TestSensorLabelSuffix tests that a sensor label (other than the sensor address) identifies the
+// sensor in log lines
+func TestSensorLabelSuffix(t *testing.T) {
+	controller := &BLEController{bleConfig: config.BLEConfig{SensorUUID: "F1:42:D8:DE:35:16", SensorLabel: "rear wheel"}}
+	assert.Equal(t, " (rear wheel)", controller.sensorLabelSuffix())
+
+	controller.bleConfig.SensorLabel = controller.bleConfig.SensorUUID
+	assert.Empty(t, controller.sensorLabelSuffix())
+
+	controller.bleConfig.SensorLabel = ""
+	assert.Empty(t, controller.sensorLabelSuffix())
+}
+
+// TestAwaitScan tests that a scan is stopped and its goroutine joined when the context is done
+func TestAwaitScan(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name        string
+		cancel      bool
+		cancelFirst bool
+		startDelay  time.Duration
+		scanErr     error
+		find        bool
+		wantErr     error
+		wantStops   int
+	}{
+		{name: "peripheral found", find: true},
+		{name: "scan fails", scanErr: errors.New("adapter not powered"), wantErr: errors.New("adapter not powered")},
+		{name: "cancelled mid-scan", cancel: true, wantErr: context.Canceled, wantStops: 1},
+		{name: "cancelled before scan starts", cancelFirst: true, startDelay: 50 * time.Millisecond, wantErr: context.Canceled, wantStops: 2},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var stops int
+			var scanExited bool
+			started := make(chan struct{})
+			stopped := make(chan struct{})
+
+			// Fake scan that runs until stopped, like the BLE adapter
+			scan := func(found chan<- bluetooth.ScanResult) error {
+				defer func() { scanExited = true }()
+
+				time.Sleep(tt.startDelay)
+				close(started)
+
+				if tt.scanErr != nil {
+					return tt.scanErr
+				}
+
+				if tt.find {
+					found <- bluetooth.ScanResult{}
+					return nil
+				}
+
+				<-stopped
+				time.Sleep(10 * time.Millisecond) // the adapter takes a moment to stop
+
+				return nil
+			}
+
+			// Fake stop scan that fails until the scan has started, like the BLE adapter
+			stopScan := func() error {
+				stops++
+
+				select {
+				case <-started:
+				default:
+					return errors.New("not scanning")
+				}
+
+				close(stopped)
+				return nil
+			}
+
+			if tt.cancel {
+				time.AfterFunc(20*time.Millisecond, cancel)
+			}
+
+			if tt.cancelFirst {
+				cancel()
+			}
+
+			_, err := awaitScan(ctx, scan, stopScan)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantStops, stops, "stop scan count mismatch")
+
+			if tt.cancel || tt.cancelFirst {
+				assert.True(t, scanExited, "scan goroutine still running after cancellation")
+			}
+
+		})
+	}
+
+}
+
+// TestProcessBLEDataFragmented tests reassembly of a CSC frame split across notifications
+func TestProcessBLEDataFragmented(t *testing.T) {
+	controller := &BLEController{speedConfig: config.SpeedConfig{
+		SpeedUnits:           config.SpeedUnitsKMH,
+		WheelCircumferenceMM: 2000,
+	}}
+
+	// Define test cases
+	tests := []struct {
+		name      string
+		fragments [][]byte
+		stale     bool
+		wantOK    []bool
+		wantSpeed float64
+	}{
+		{
+			name: "frame split into two notifications",
+			fragments: [][]byte{
+				{0x01, 0x03, 0x00, 0x00}, // flags, partial wheel revs
+				{0x00, 0x40, 0x00},       // rest of wheel revs, wheel event time
+			},
+			wantOK:    []bool{false, true},
+			wantSpeed: 64.0, // (1 rev * 2000mm) / 32/1024ths of a second, in m/s
+		},
+		{
+			name: "frame with crank data split into two notifications",
+			fragments: [][]byte{
+				{0x03, 0x03, 0x00, 0x00, 0x00, 0x40}, // flags, wheel revs, partial wheel event time
+				{0x00, 0x05, 0x00, 0x10, 0x00},       // rest of wheel event time, crank data
+			},
+			wantOK:    []bool{false, true},
+			wantSpeed: 64.0,
+		},
+		{
+			name: "garbage partial frame followed by a complete frame",
+			fragments: [][]byte{
+				{0x01, 0xFF},
+				{0x01, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00},
+			},
+			wantOK:    []bool{false, true},
+			wantSpeed: 64.0,
+		},
+		{
+			name: "stale partial frame is discarded",
+			fragments: [][]byte{
+				{0x01, 0x03, 0x00, 0x00},
+				{0x00, 0x40, 0x00},
+			},
+			stale:     true,
+			wantOK:    []bool{false, true},
+			wantSpeed: 0.0, // remaining bytes alone are rejected as invalid data
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Establish the initial wheel revs and time
+			decoding = decodeState{}
+			controller.processBLEData([]byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00})
+
+			var got float64
+
+			for i, fragment := range tt.fragments {
+
+				if tt.stale && i > 0 {
+					decoding.pendingFrameTime = time.Now().Add(-frameReassemblyTimeout)
+				}
+
+				var ok bool
+				got, _, ok = controller.processBLEData(fragment)
+				assert.Equal(t, tt.wantOK[i], ok, "fragment %d completion mismatch", i)
+			}
+
+			assert.InDelta(t, tt.wantSpeed, got, 0.1, "speed calculation mismatch")
+			assert.Empty(t, decoding.pendingFrame, "partial frame left buffered")
+		})
+	}
+
+}
+
+// fakeAdvertisement is a fabricated advertisement payload listing service UUIDs
+type fakeAdvertisement struct {
+	serviceUUIDs []bluetooth.UUID
+}
+
+func (a fakeAdvertisement) LocalName() string { return "" }
+func (a fakeAdvertisement) Bytes() []byte     { return nil }
+
+func (a fakeAdvertisement) ManufacturerData() []bluetooth.ManufacturerDataElement { return nil }
+func (a fakeAdvertisement) ServiceData() []bluetooth.ServiceDataElement           { return nil }
+
+func (a fakeAdvertisement) HasServiceUUID(uuid bluetooth.UUID) bool {
+	return slices.Contains(a.serviceUUIDs, uuid)
+}
+
+// TestMatchesScanFilter tests matching scan results by address and advertised service UUID
+func TestMatchesScanFilter(t *testing.T) {
+	const sensorAddress = "F1:42:D8:DE:35:16"
+
+	csc := bluetooth.New16BitUUID(0x1816)
+	heartRate := bluetooth.New16BitUUID(0x180D)
+
+	// Define test cases
+	tests := []struct {
+		name        string
+		address     string
+		services    []bluetooth.UUID
+		serviceUUID string
+		want        bool
+	}{
+		{
+			name:    "address match without filter",
+			address: sensorAddress,
+			want:    true,
+		},
+		{
+			name:    "address mismatch without filter",
+			address: "11:22:33:44:55:66",
+			want:    false,
+		},
+		{
+			name:        "address and service match",
+			address:     sensorAddress,
+			services:    []bluetooth.UUID{heartRate, csc},
+			serviceUUID: "1816",
+			want:        true,
+		},
+		{
+			name:        "address and 128-bit service match",
+			address:     sensorAddress,
+			services:    []bluetooth.UUID{csc},
+			serviceUUID: "00001816-0000-1000-8000-00805f9b34fb",
+			want:        true,
+		},
+		{
+			name:        "service not advertised",
+			address:     sensorAddress,
+			services:    []bluetooth.UUID{heartRate},
+			serviceUUID: "1816",
+			want:        false,
+		},
+		{
+			name:        "service match with address mismatch",
+			address:     "11:22:33:44:55:66",
+			services:    []bluetooth.UUID{csc},
+			serviceUUID: "1816",
+			want:        false,
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serviceUUID, err := parseServiceUUID(tt.serviceUUID)
+			assert.NoError(t, err)
+
+			mac, err := bluetooth.ParseMAC(tt.address)
+			assert.NoError(t, err)
+
+			result := bluetooth.ScanResult{
+				Address:              bluetooth.Address{MACAddress: bluetooth.MACAddress{MAC: mac}},
+				AdvertisementPayload: fakeAdvertisement{serviceUUIDs: tt.services},
+			}
+
+			assert.Equal(t, tt.want, matchesScanFilter(result, sensorAddress, serviceUUID))
+		})
+	}
+
+}
+
+// TestResolveAmbiguousZero tests both ambiguous zero policies on zero speeds between valid frames
+func TestResolveAmbiguousZero(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name   string
+		policy string
+		speeds []float64
+		want   []float64
+	}{
+		{"report zero between valid frames", config.AmbiguousZeroReport, []float64{5.0, 0.0, 5.0}, []float64{5.0, 0.0, 5.0}},
+		{"hold over zero between valid frames", config.AmbiguousZeroHold, []float64{5.0, 0.0, 5.0}, []float64{5.0, 5.0, 5.0}},
+		{"hold for a single frame only", config.AmbiguousZeroHold, []float64{5.0, 0.0, 0.0, 0.0}, []float64{5.0, 5.0, 0.0, 0.0}},
+		{"hold doesn't apply from a stop", config.AmbiguousZeroHold, []float64{0.0, 0.0, 4.0}, []float64{0.0, 0.0, 4.0}},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoding = decodeState{}
+			controller := &BLEController{speedConfig: config.SpeedConfig{AmbiguousZeroPolicy: tt.policy}}
+
+			for i, sensorSpeed := range tt.speeds {
+				assert.Equal(t, tt.want[i], controller.resolveAmbiguousZero(sensorSpeed), "frame %d", i)
+			}
+
+		})
+	}
+
+}
+
+// TestMatchesAddressFormats tests matching a scanned address against sensor addresses that differ
+// only in case or separator formatting
+func TestMatchesAddressFormats(t *testing.T) {
+	mac, err := bluetooth.ParseMAC("F1:42:D8:DE:35:16")
+	assert.NoError(t, err)
+
+	result := bluetooth.ScanResult{Address: bluetooth.Address{MACAddress: bluetooth.MACAddress{MAC: mac}}}
+
+	for _, address := range []string{"F1:42:D8:DE:35:16", "f1:42:d8:de:35:16", "F1-42-D8-DE-35-16", "f142d8de3516", " F1:42:D8:DE:35:16 "} {
+		assert.True(t, matchesScanFilter(result, address, nil), "address %q should match", address)
+	}
+
+	assert.False(t, matchesScanFilter(result, "F1:42:D8:DE:35:17", nil))
+}
+
+// TestRetryDiscovery tests retrying service discovery that comes up empty right after connecting
+func TestRetryDiscovery(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name      string
+		results   [][]string
+		errs      []error
+		retries   int
+		wantErr   bool
+		wantCalls int
+	}{
+		{
+			name:      "found on first attempt",
+			results:   [][]string{{"csc"}},
+			errs:      []error{nil},
+			retries:   3,
+			wantCalls: 1,
+		},
+		{
+			name:      "empty then found",
+			results:   [][]string{{}, {"csc"}},
+			errs:      []error{nil, nil},
+			retries:   3,
+			wantCalls: 2,
+		},
+		{
+			name:      "error then found",
+			results:   [][]string{nil, {"csc"}},
+			errs:      []error{errors.New("service not found"), nil},
+			retries:   3,
+			wantCalls: 2,
+		},
+		{
+			name:      "empty without retries",
+			results:   [][]string{{}},
+			errs:      []error{nil},
+			retries:   0,
+			wantErr:   true,
+			wantCalls: 1,
+		},
+		{
+			name:      "empty after all retries",
+			results:   [][]string{{}, {}, {}},
+			errs:      []error{nil, nil, nil},
+			retries:   2,
+			wantErr:   true,
+			wantCalls: 3,
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+
+			found, err := retryDiscovery(context.Background(), tt.retries, time.Millisecond, func() ([]string, error) {
+				calls++
+				return tt.results[calls-1], tt.errs[calls-1]
+			})
+
+			assert.Equal(t, tt.wantErr, err != nil, "unexpected discovery error: %v", err)
+			assert.Equal(t, tt.wantCalls, calls, "discovery attempt count mismatch")
+
+			if !tt.wantErr {
+				assert.Equal(t, []string{"csc"}, found)
+			}
+
+		})
+	}
+
+}
+
+// TestMissingCSCServiceError tests the diagnostic for a peripheral without the CSC service
+func TestMissingCSCServiceError(t *testing.T) {
+	customUUID, err := bluetooth.ParseUUID("6e400001-b5a3-f393-e0a9-e50e24dcca9e")
+	assert.NoError(t, err)
+
+	// Stubbed device services: a heart rate monitor with a custom service
+	uuids := []bluetooth.UUID{
+		bluetooth.New16BitUUID(0x180D),
+		bluetooth.New16BitUUID(0x180F),
+		bluetooth.New16BitUUID(0x2A00),
+		customUUID,
+	}
+
+	err = missingCSCServiceError("F1:42:D8:DE:35:16", uuids)
+	assert.ErrorContains(t, err, "F1:42:D8:DE:35:16")
+	assert.ErrorContains(t, err, "Heart Rate (0x180D), Battery (0x180F), 0x2A00, 6e400001-b5a3-f393-e0a9-e50e24dcca9e")
+	assert.ErrorContains(t, err, "sensor_uuid may be wrong")
+
+	// A device without any services
+	assert.ErrorContains(t, missingCSCServiceError("F1:42:D8:DE:35:16", nil), "exposes no services")
+}
+
+// TestProcessBLEDataUndersized tests that frames too short for the sensor data they report are
+// counted and rejected, rather than reported as a zero speed
+func TestProcessBLEDataUndersized(t *testing.T) {
+	controller := &BLEController{speedConfig: config.SpeedConfig{
+		SpeedUnits:           config.SpeedUnitsKMH,
+		WheelCircumferenceMM: 2000,
+	}}
+
+	// Define test cases
+	tests := []struct {
+		name          string
+		stalePartial  []byte
+		data          []byte
+		wantMinLength int
+		wantOK        bool
+	}{
+		{
+			name:          "empty frame",
+			data:          []byte{},
+			wantMinLength: minFlagsFrameLength,
+		},
+		{
+			name:          "crank frame",
+			data:          []byte{0x02, 0x0A, 0x00},
+			wantMinLength: minCrankFrameLength,
+		},
+		{
+			name:          "stale partial wheel frame",
+			stalePartial:  []byte{0x01, 0x03, 0x00, 0x00},
+			data:          []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00},
+			wantMinLength: minWheelFrameLength,
+			wantOK:        true, // the following complete frame is still decoded
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoding = decodeState{pendingFrame: tt.stalePartial, pendingFrameTime: time.Now().Add(-2 * frameReassemblyTimeout)}
+
+			undersized := tt.data
+
+			if tt.stalePartial != nil {
+				undersized = tt.stalePartial
+			}
+
+			assert.Equal(t, tt.wantMinLength, minFrameLength(undersized, controller.bleConfig.Quirks))
+
+			_, _, ok := controller.processBLEData(tt.data)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, 1, decoding.undersizedFrames, "undersized frame should be counted")
+		})
+	}
+
+	// Frames of the minimum length are not counted
+	decoding.undersizedFrames = 0
+	controller.processBLEData([]byte{0x02, 0x0A, 0x00, 0x00, 0x04})
+	controller.processBLEData([]byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00})
+	assert.Zero(t, decoding.undersizedFrames)
+}
+
+// TestProcessBLEDataQuirks tests that decoding quirks change CSC measurement decoding
+func TestProcessBLEDataQuirks(t *testing.T) {
+	speedConfig := config.SpeedConfig{
+		SpeedUnits:           config.SpeedUnitsKMH,
+		WheelCircumferenceMM: 2000,
+	}
+
+	// Define test cases
+	tests := []struct {
+		name      string
+		quirks    config.QuirksConfig
+		first     []byte
+		next      []byte
+		wantSpeed float64
+	}{
+		{
+			name:      "missing wheel flag without quirks",
+			first:     []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00},
+			next:      []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00},
+			wantSpeed: 0.0,
+		},
+		{
+			name:      "missing wheel flag with forced wheel data",
+			quirks:    config.QuirksConfig{ForceWheelData: true},
+			first:     []byte{0x00, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00},
+			next:      []byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00},
+			wantSpeed: 64.0, // (1 rev * 2000mm) / 32/1024ths of a second, in m/s
+		},
+		{
+			name:   "swapped wheel data fields",
+			quirks: config.QuirksConfig{WheelRevsOffset: 3, WheelTimeOffset: 1},
+			first: []byte{
+				0x01,       // flags
+				0x20, 0x00, // wheel event time
+				0x02, 0x00, 0x00, 0x00, // wheel revs
+			},
+			next: []byte{
+				0x01,       // flags
+				0x40, 0x00, // wheel event time
+				0x03, 0x00, 0x00, 0x00, // wheel revs
+			},
+			wantSpeed: 64.0,
+		},
+		{
+			name:      "millisecond wheel event times",
+			quirks:    config.QuirksConfig{WheelTimeResolution: 1000},
+			first:     []byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00},
+			next:      []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00},
+			wantSpeed: 62.5, // (1 rev * 2000mm) / 32/1000ths of a second, in m/s
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := &BLEController{bleConfig: config.BLEConfig{Quirks: tt.quirks}, speedConfig: speedConfig}
+
+			// Establish the initial wheel revs and time
+			decoding = decodeState{}
+			controller.processBLEData(tt.first)
+
+			got, _, _ := controller.processBLEData(tt.next)
+			assert.InDelta(t, tt.wantSpeed, got, 0.1, "speed calculation mismatch")
+		})
+	}
+
+}
+
+// TestProcessBLEDataRevolutionWrap tests speed calculation across the 32-bit wheel revolution boundary
+func TestProcessBLEDataRevolutionWrap(t *testing.T) {
+	controller := &BLEController{speedConfig: config.SpeedConfig{
+		SpeedUnits:           config.SpeedUnitsKMH,
+		WheelCircumferenceMM: 2000,
+	}}
+
+	// Define test cases
+	tests := []struct {
+		name  string
+		first []byte
+		next  []byte
+		want  float64
+	}{
+		{
+			name:  "single revolution across wrap",
+			first: []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0x20, 0x00}, // wheel revs 0xFFFFFFFF
+			next:  []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x40, 0x00}, // wheel revs 0x00000000
+			want:  64.0,                                             // (1 rev * 2000mm) / 32/1024ths of a second, in m/s
+		},
+		{
+			name:  "multiple revolutions across wrap",
+			first: []byte{0x01, 0xFE, 0xFF, 0xFF, 0xFF, 0x20, 0x00}, // wheel revs 0xFFFFFFFE
+			next:  []byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x40, 0x00}, // wheel revs 0x00000001
+			want:  192.0,                                            // (3 revs * 2000mm) / 32/1024ths of a second, in m/s
+		},
+		{
+			name:  "event time and revolutions across wrap",
+			first: []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xF0, 0xFF}, // wheel revs 0xFFFFFFFF, time 0xFFF0
+			next:  []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x10, 0x00}, // wheel revs 0x00000000, time 0x0010
+			want:  64.0,                                             // (1 rev * 2000mm) / 32/1024ths of a second, in m/s
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reset the decoding state and prime the last wheel revs and time
+			decoding = decodeState{}
+			controller.processBLEData(tt.first)
+
+			got, _, ok := controller.processBLEData(tt.next)
+			assert.True(t, ok)
+			assert.InDelta(t, tt.want, got, 0.1, "speed calculation mismatch")
+		})
+	}
+
+}
+
+// TestProcessBLEDataDuplicate tests that a duplicated measurement between two real measurements
+// is ignored, leaving the decoding state unchanged
+func TestProcessBLEDataDuplicate(t *testing.T) {
+	controller := &BLEController{speedConfig: config.SpeedConfig{
+		SpeedUnits:           config.SpeedUnitsKMH,
+		WheelCircumferenceMM: 2000,
+	}}
+
+	first := []byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00}
+	next := []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00}
+
+	// Reset the decoding state and prime the last wheel revs and time
+	decoding = decodeState{}
+	controller.processBLEData(first)
+	primed := decoding
+
+	// Duplicate measurement should be ignored
+	got, _, ok := controller.processBLEData(first)
+	assert.False(t, ok, "duplicate measurement should be ignored")
+	assert.Zero(t, got)
+	assert.Equal(t, primed, decoding, "duplicate measurement should not change the decoding state")
+
+	// Next measurement should be calculated against the original (not the duplicate) measurement
+	got, _, ok = controller.processBLEData(next)
+	assert.True(t, ok, "new measurement should not be ignored")
+	assert.InDelta(t, 64.0, got, 0.1, "speed calculation mismatch") // (1 rev * 2000mm) / 32/1024ths of a second, in m/s
+}
+
+// TestProcessBLECadence tests that crank data feeds the cadence controller, with or without wheel data
+func TestProcessBLECadence(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name   string
+		frames [][]byte
+	}{
+		{
+			name: "wheel and crank data",
+			frames: [][]byte{
+				{0x03, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00, 0x0A, 0x00, 0x00, 0x04}, // crank revs 10, time 1024
+				{0x03, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00, 0x0B, 0x00, 0x00, 0x08}, // 1 rev in 1s
+				{0x03, 0x04, 0x00, 0x00, 0x00, 0x60, 0x00, 0x0D, 0x00, 0x00, 0x0C}, // 2 revs in 1s
+			},
+		},
+		{
+			name: "crank data only",
+			frames: [][]byte{
+				{0x02, 0x0A, 0x00, 0x00, 0x04},
+				{0x02, 0x0B, 0x00, 0x00, 0x08},
+				{0x02, 0x0D, 0x00, 0x00, 0x0C},
+			},
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cadenceController := speed.NewCadenceController(2)
+			controller := &BLEController{speedConfig: config.SpeedConfig{
+				SpeedUnits:           config.SpeedUnitsKMH,
+				WheelCircumferenceMM: 2000,
+			}}
+			controller.SetCadenceController(cadenceController)
+
+			decoding = decodeState{}
+
+			for _, frame := range tt.frames {
+				controller.processBLEData(frame)
+			}
+
+			assert.InDelta(t, 120.0, cadenceController.Cadence(), 0.01, "cadence mismatch")
+			assert.InDelta(t, 90.0, cadenceController.AverageCadence(), 0.01, "average cadence mismatch")
+		})
+	}
+
+}
+
+// TestProcessBLECadenceSpeed tests that the cadence of a cadence-only sensor drives the speed
+// (and so playback) when a cadence to speed factor is configured
+func TestProcessBLECadenceSpeed(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name       string
+		factor     float64
+		frames     [][]byte
+		wantSpeeds []float64
+	}{
+		{
+			name:   "crank data only, with a cadence to speed factor",
+			factor: 5.0,
+			frames: [][]byte{
+				{0x02, 0x0A, 0x00, 0x00, 0x04}, // crank revs 10, time 1024
+				{0x02, 0x0B, 0x00, 0x00, 0x08}, // 1 rev in 1s (60 RPM)
+				{0x02, 0x0D, 0x00, 0x00, 0x0C}, // 2 revs in 1s (120 RPM)
+			},
+			wantSpeeds: []float64{5.0, 10.0}, // RPM * 5m per rev / 60s, in m/s
+		},
+		{
+			name: "crank data only, without a cadence to speed factor",
+			frames: [][]byte{
+				{0x02, 0x0A, 0x00, 0x00, 0x04},
+				{0x02, 0x0B, 0x00, 0x00, 0x08},
+				{0x02, 0x0D, 0x00, 0x00, 0x0C},
+			},
+			wantSpeeds: []float64{},
+		},
+		{
+			name:   "wheel data takes precedence over cadence",
+			factor: 5.0,
+			frames: [][]byte{
+				{0x03, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00, 0x0A, 0x00, 0x00, 0x04},
+				{0x03, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00, 0x0B, 0x00, 0x00, 0x08},
+			},
+			wantSpeeds: []float64{0.0, 64.0}, // (1 rev * 2000mm) / 32/1024ths of a second, in m/s
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := &BLEController{speedConfig: config.SpeedConfig{
+				SpeedUnits:           config.SpeedUnitsKMH,
+				WheelCircumferenceMM: 2000,
+				CadenceToSpeedFactor: tt.factor,
+			}}
+
+			decoding = decodeState{}
+			speeds := []float64{}
+
+			for _, frame := range tt.frames {
+
+				if sensorSpeed, _, ok := controller.processBLEData(frame); ok {
+					speeds = append(speeds, sensorSpeed)
+				}
+
+			}
+
+			assert.InDeltaSlice(t, tt.wantSpeeds, speeds, 0.01, "speed mismatch")
+		})
+	}
+
+}
+
+// TestProcessBLEDataCrankOnly tests that a crank-only frame, without a cadence to speed factor,
+// is ignored for speed, leaving the last sensor speed unchanged
+func TestProcessBLEDataCrankOnly(t *testing.T) {
+	controller := &BLEController{speedConfig: config.SpeedConfig{
+		SpeedUnits:           config.SpeedUnitsKMH,
+		WheelCircumferenceMM: 2000,
+	}}
+
+	// Establish a wheel speed
+	decoding = decodeState{}
+	controller.processBLEData([]byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00})
+	wheelSpeed, _, ok := controller.processBLEData([]byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00})
+	assert.True(t, ok)
+	assert.InDelta(t, 64.0, wheelSpeed, 0.1)
+
+	for _, frame := range [][]byte{
+		{0x02, 0x0A, 0x00, 0x00, 0x04}, // crank revs 10, time 1024
+		{0x02, 0x0B, 0x00, 0x00, 0x08}, // 1 rev in 1s (60 RPM)
+	} {
+		got, _, ok := controller.processBLEData(frame)
+		assert.False(t, ok, "crank-only frame (% x) should be ignored for speed", frame)
+		assert.Zero(t, got)
+		assert.InDelta(t, wheelSpeed, decoding.lastSensorSpeed, 0.0001, "last sensor speed should be unchanged")
+	}
+
+}
+
+// TestRunStartupPhase tests that the startup budget aborts the startup sequence in the phase it
+// was in when the budget expired, and that a phase succeeding after that is cleaned up
+func TestRunStartupPhase(t *testing.T) {
+	controller := &BLEController{startupDeadline: time.Now().Add(50 * time.Millisecond)}
+	ctx, cancel := controller.startupContext(context.Background())
+	defer cancel()
+
+	// A phase completing within the budget succeeds
+	result, err := runStartupPhase(ctx, phaseScan, func() (string, error) { return "found", nil }, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "found", result)
+
+	// A phase failing within the budget reports its own error
+	_, err = runStartupPhase(ctx, phaseConnect, func() (string, error) { return "", errors.New("connection refused") }, nil)
+	assert.EqualError(t, err, "connection refused")
+
+	// A phase still running when the budget expires reports the phase, and its late success is
+	// cleaned up
+	release := make(chan struct{})
+	cleaned := make(chan string, 1)
+
+	_, err = runStartupPhase(ctx, phaseConnect, func() (string, error) {
+		<-release
+		return "too late", nil
+	}, func(value string) { cleaned <- value })
+	assert.EqualError(t, err, "BLE startup budget exceeded during connect phase")
+
+	close(release)
+
+	select {
+	case value := <-cleaned:
+		assert.Equal(t, "too late", value)
+	case <-time.After(time.Second):
+		t.Fatalf("late phase success was not cleaned up")
+	}
+
+	// Later phases also report the expired budget
+	_, err = runStartupPhase(ctx, phaseSubscribe, func() (string, error) { return "", errors.New("context deadline exceeded") }, nil)
+	assert.EqualError(t, err, "BLE startup budget exceeded during subscribe phase")
+
+	// Without a budget, only cancellation stops a phase
+	controller = &BLEController{}
+	ctx, cancel = controller.startupContext(context.Background())
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline, "startup context should have no deadline without a budget")
+
+	cancel()
+
+	release = make(chan struct{})
+	defer close(release)
+
+	_, err = runStartupPhase(ctx, phaseScan, func() (string, error) {
+		<-release
+		return "", nil
+	}, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// cscFixture is a recorded sequence of CSC measurement notifications with their expected speeds
+type cscFixture struct {
+	circumferenceMM int
+	steps           []cscFixtureStep
+}
+
+// cscFixtureStep is a single notification in a CSC fixture, and its expected decoded speed
+type cscFixtureStep struct {
+	data      []byte
+	wantSpeed float64
+	wantOK    bool
+}
+
+// loadCSCFixture loads a CSC fixture file, in which each line is either a comment (#), a
+// "circumference_mm <mm>" setting, or notification hex bytes followed by the expected speed (in
+// meters per second) or "-" if the notification should be ignored
+func loadCSCFixture(path string) (cscFixture, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return cscFixture{}, err
+	}
+	defer file.Close()
+
+	var fixture cscFixture
+	scanner := bufio.NewScanner(file)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if fields[0] == "circumference_mm" && len(fields) == 2 {
+
+			if fixture.circumferenceMM, err = strconv.Atoi(fields[1]); err != nil {
+				return cscFixture{}, fmt.Errorf("line %d: invalid circumference: %w", lineNum, err)
+			}
+
+			continue
+		}
+
+		if len(fields) < 2 {
+			return cscFixture{}, fmt.Errorf("line %d: missing expected speed", lineNum)
+		}
+
+		step, err := parseCSCFixtureStep(fields)
+		if err != nil {
+			return cscFixture{}, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+
+		fixture.steps = append(fixture.steps, step)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return cscFixture{}, err
+	}
+
+	if fixture.circumferenceMM == 0 || len(fixture.steps) == 0 {
+		return cscFixture{}, errors.New("fixture needs a circumference_mm setting and at least one notification")
+	}
+
+	return fixture, nil
+}
+
+// parseCSCFixtureStep parses notification hex bytes followed by the expected speed (or "-")
+func parseCSCFixtureStep(fields []string) (cscFixtureStep, error) {
+	data, err := hex.DecodeString(strings.Join(fields[:len(fields)-1], ""))
+	if err != nil {
+		return cscFixtureStep{}, fmt.Errorf("invalid notification bytes: %w", err)
+	}
+
+	step := cscFixtureStep{data: data}
+	want := fields[len(fields)-1]
+
+	if want == "-" {
+		return step, nil
+	}
+
+	if step.wantSpeed, err = strconv.ParseFloat(want, 64); err != nil {
+		return cscFixtureStep{}, fmt.Errorf("invalid expected speed: %w", err)
+	}
+
+	step.wantOK = true
+
+	return step, nil
+}
+
+// TestProcessBLEDataFixtures replays each CSC fixture in testdata/csc through the decoder
+func TestProcessBLEDataFixtures(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "csc", "*.hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(paths) == 0 {
+		t.Fatal("no CSC fixtures found in testdata/csc")
+	}
+
+	// Run tests
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".hex"), func(t *testing.T) {
+			fixture, err := loadCSCFixture(path)
+			if err != nil {
+				t.Fatalf("failed to load fixture: %v", err)
+			}
+
+			controller := &BLEController{speedConfig: config.SpeedConfig{
+				SpeedUnits:           config.SpeedUnitsKMH,
+				WheelCircumferenceMM: fixture.circumferenceMM,
+			}}
+
+			decoding = decodeState{}
+
+			for i, step := range fixture.steps {
+				got, _, ok := controller.processBLEData(step.data)
+				assert.Equal(t, step.wantOK, ok, "notification %d (% x) accepted mismatch", i+1, step.data)
+				assert.InDelta(t, step.wantSpeed, got, 0.01, "notification %d (% x) speed mismatch", i+1, step.data)
+			}
+
+		})
+	}
+
+}
+
+// TestLoadCSCFixture tests parsing of the CSC fixture format
+func TestLoadCSCFixture(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name    string
+		content string
+		want    cscFixture
+		wantErr bool
+	}{
+		{
+			name:    "valid fixture",
+			content: "# comment\n\ncircumference_mm 2000\n01 02 00 00 00 20 00  0.00\n0103000000 4000 -\n",
+			want: cscFixture{
+				circumferenceMM: 2000,
+				steps: []cscFixtureStep{
+					{data: []byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x20, 0x00}, wantOK: true},
+					{data: []byte{0x01, 0x03, 0x00, 0x00, 0x00, 0x40, 0x00}},
+				},
+			},
+		},
+		{
+			name:    "invalid hex",
+			content: "circumference_mm 2000\n01 0g  0.00\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing expected speed",
+			content: "circumference_mm 2000\n0102000000\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing circumference",
+			content: "01 02 00 00 00 20 00  0.00\n",
+			wantErr: true,
+		},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "fixture.hex")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadCSCFixture(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+}
+
+// Speed pipeline benchmark parameters
+const (
+	pipelineSmoothingWindow = 5
+	pipelineTestFrames      = 1000
+)
+
+// runSpeedPipeline feeds synthetic CSC frames through BLE speed processing and speed smoothing
+func runSpeedPipeline(controller *BLEController, speedController *speed.SpeedController, frames int) {
+	frame := make([]byte, 7)
+	frame[0] = 0x01 // flags
+
+	for i := 1; i <= frames; i++ {
+		binary.LittleEndian.PutUint32(frame[1:], uint32(i))     // wheel revs (1 revolution per frame)
+		binary.LittleEndian.PutUint16(frame[5:], uint16(i*512)) // wheel event time (0.5 seconds per frame)
+
+		if sensorSpeed, _, ok := controller.processBLEData(frame); ok {
+			speedController.UpdateSpeed(sensorSpeed)
+		}
+
+	}
+
+}
+
+// quietLogging raises the logging level for the duration of a test so that per-frame logging is suppressed
+func quietLogging(tb testing.TB) {
+	logger.Initialize("error")
+	tb.Cleanup(func() { logger.Initialize("debug") })
+}
+
+// BenchmarkSpeedPipeline benchmarks the BLE notification path (CSC frame decoding and speed smoothing)
+func BenchmarkSpeedPipeline(b *testing.B) {
+	controller := &BLEController{speedConfig: config.SpeedConfig{
+		SpeedUnits:           config.SpeedUnitsKMH,
+		WheelCircumferenceMM: 2000,
+	}}
+
+	quietLogging(b)
+	decoding = decodeState{}
+	speedController := speed.NewSpeedController(pipelineSmoothingWindow)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	runSpeedPipeline(controller, speedController, b.N)
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "notifications/s")
+}
+
+// TestSpeedPipeline tests that the speed pipeline benchmark runs without error on a short budget
+func TestSpeedPipeline(t *testing.T) {
+	controller := &BLEController{speedConfig: config.SpeedConfig{
+		SpeedUnits:           config.SpeedUnitsKMH,
+		WheelCircumferenceMM: 2000,
+	}}
+
+	quietLogging(t)
+	decoding = decodeState{}
+	speedController := speed.NewSpeedController(pipelineSmoothingWindow)
+
+	runSpeedPipeline(controller, speedController, pipelineTestFrames)
+
+	// 1 rev * 2000mm / 512/1024ths of a second, in m/s
+	got := speedController.GetSmoothedSpeed()
+	assert.False(t, math.IsNaN(got) || math.IsInf(got, 0), "smoothed speed should be finite")
+	assert.InDelta(t, 4.00, got, 0.01, "smoothed speed mismatch")
+}
@@ -137,7 +137,7 @@ func TestProcessBLESpeed(t *testing.T) {
 				0x40, 0x00, // wheel event time (32 time units later)
 			},
 			speedUnits: speedUnitsKMH,
-			want:       225.0, // (1 rev * 2000mm * 3.6) / 32 time units
+			want:       64.0, // (1 rev * 2000mm) / 32/1024ths of a second, in m/s
 		},
 		{
 			name: validDataMPHFirst,
@@ -173,7 +173,7 @@ func TestProcessBLESpeed(t *testing.T) {
 			}
 
 			// Process BLE data
-			got := controller.ProcessBLESpeed(tt.data)
+			got, _ := controller.ProcessBLESpeed(tt.data)
 			assert.InDelta(t, tt.want, got, 0.1, "Speed calculation mismatch")
 		})
 	}
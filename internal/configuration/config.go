@@ -3,8 +3,12 @@ package config
 import (
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
@@ -21,39 +25,158 @@ const (
 	// Speed units
 	SpeedUnitsKMH = "km/h"
 	SpeedUnitsMPH = "mph"
+
+	// Speed sources
+	SourceBLE  = "ble"
+	SourceDemo = "demo"
+
+	// End of video behaviors
+	OnEndStop = "stop"
+	OnEndHold = "hold"
+	OnEndLoop = "loop"
+
+	// Video behaviors without a display
+	HeadlessFail      = "fail"
+	HeadlessAudioOnly = "audio_only"
+
+	// Component shutdown orders
+	ShutdownVideoFirst = "video_first"
+	ShutdownBLEFirst   = "ble_first"
+
+	// Ambiguous zero speed policies
+	AmbiguousZeroReport = "zero"
+	AmbiguousZeroHold   = "hold"
+
+	// Demo speed profiles
+	DemoProfileSine     = "sine"
+	DemoProfileSawtooth = "sawtooth"
+
+	// Video speed filter placeholders
+	SpeedFilterSpeed = "{speed}"
+	SpeedFilterRate  = "{rate}"
+
+	// CSC wheel event time units per second, per the CSC specification
+	DefaultWheelTimeResolution = 1024
+
+	// Largest CSC measurement field offset allowed by decoding quirks (BLE notifications are
+	// typically no more than 20 bytes)
+	maxQuirkFieldOffset = 16
+
+	// Plausible bicycle wheel circumference range (millimeters)
+	minPlausibleWheelMM = 900
+	maxPlausibleWheelMM = 2400
+
+	// Millimeters per unit commonly entered by mistake
+	mmPerInch       = 25.4
+	mmPerCentimeter = 10.0
+	mmPerMeter      = 1000.0
 )
 
+// Supported configuration values, e.g., for reporting the capabilities of this build
+var (
+	SupportedSpeedUnits   = []string{SpeedUnitsKMH, SpeedUnitsMPH}
+	SupportedSources      = []string{SourceBLE, SourceDemo}
+	SupportedOnEnds       = []string{OnEndStop, OnEndHold, OnEndLoop}
+	SupportedDemoProfiles = []string{DemoProfileSine, DemoProfileSawtooth}
+)
+
+// sensorAddressPattern matches a BLE peripheral MAC address (e.g., "F1:42:D8:DE:35:16", in either
+// case, and with colons, dashes, or no separators) or a 128-bit peripheral UUID (as used on macOS)
+var sensorAddressPattern = regexp.MustCompile(`^([0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){5}|[0-9A-Fa-f]{2}(-[0-9A-Fa-f]{2}){5}|[0-9A-Fa-f]{12}|[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12})$`)
+
+// serviceUUIDPattern matches a 16-bit (e.g., "1816") or 128-bit BLE service UUID
+var serviceUUIDPattern = regexp.MustCompile(`^([0-9A-Fa-f]{4}|[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12})$`)
+
+// speedFilterPattern matches speed filters built from safe MPV filter syntax and placeholders
+var speedFilterPattern = regexp.MustCompile(`^[A-Za-z0-9_=:.,@\[\]{}-]+$`)
+
 // Config represents the application configuration
 type Config struct {
-	App   AppConfig   `toml:"app"`
-	BLE   BLEConfig   `toml:"ble"`
-	Speed SpeedConfig `toml:"speed"`
-	Video VideoConfig `toml:"video"`
+	App     AppConfig     `toml:"app"`
+	BLE     BLEConfig     `toml:"ble"`
+	Speed   SpeedConfig   `toml:"speed"`
+	Cadence CadenceConfig `toml:"cadence"`
+	Video   VideoConfig   `toml:"video"`
+	TTS     TTSConfig     `toml:"tts"`
 }
 
 // AppConfig represents the application configuration
 type AppConfig struct {
-	LogLevel string `toml:"logging_level"`
+	LogLevel             string  `toml:"logging_level"`
+	DisplaySmoothingSecs float64 `toml:"display_smoothing_secs"`
+	MaxRunSecs           int     `toml:"max_run_secs"`
+	StatsWarmupSecs      float64 `toml:"stats_warmup_secs"`
+	LogLifecycle         bool    `toml:"log_lifecycle"`
+	ShutdownOrder        string  `toml:"shutdown_order"`
+	SummaryImagePath     string  `toml:"summary_image_path"`
+	HistoryMaxSamples    int     `toml:"history_max_samples"`
 }
 
 // BLEConfig represents the BLE controller configuration
 type BLEConfig struct {
-	SensorUUID      string `toml:"sensor_uuid"`
-	ScanTimeoutSecs int    `toml:"scan_timeout_secs"`
+	SensorUUID         string       `toml:"sensor_uuid"`
+	SensorLabel        string       `toml:"sensor_label"`
+	ScanTimeoutSecs    int          `toml:"scan_timeout_secs"`
+	StartupBudgetSecs  int          `toml:"startup_budget_secs"`
+	Source             string       `toml:"source"`
+	ScanServiceUUID    string       `toml:"scan_service_uuid"`
+	DiscoveryRetries   int          `toml:"discovery_retries"`
+	FallbackToSimAfter int          `toml:"fallback_to_sim_after"`
+	Demo               DemoConfig   `toml:"demo"`
+	Quirks             QuirksConfig `toml:"quirks"`
+}
+
+// DemoConfig represents the simulated (demo) speed source configuration
+type DemoConfig struct {
+	Profile    string  `toml:"profile"`
+	MinSpeed   float64 `toml:"min_speed"`
+	MaxSpeed   float64 `toml:"max_speed"`
+	PeriodSecs int     `toml:"period_secs"`
+}
+
+// QuirksConfig represents (expert-only) overrides of CSC measurement decoding for quirky sensor firmware
+type QuirksConfig struct {
+	ForceWheelData      bool `toml:"force_wheel_data"`
+	WheelRevsOffset     int  `toml:"wheel_revs_offset"`
+	WheelTimeOffset     int  `toml:"wheel_time_offset"`
+	WheelTimeResolution int  `toml:"wheel_time_resolution"`
 }
 
 // SpeedConfig represents the speed controller configuration
 type SpeedConfig struct {
-	SmoothingWindow      int     `toml:"smoothing_window"`
-	SpeedThreshold       float64 `toml:"speed_threshold"`
-	WheelCircumferenceMM int     `toml:"wheel_circumference_mm"`
-	SpeedUnits           string  `toml:"speed_units"`
+	SmoothingWindow           int     `toml:"smoothing_window"`
+	SpeedThreshold            float64 `toml:"speed_threshold"`
+	WheelCircumferenceMM      int     `toml:"wheel_circumference_mm"`
+	SpeedUnits                string  `toml:"speed_units"`
+	Invert                    bool    `toml:"invert"`
+	InvertReferenceSpeed      float64 `toml:"invert_reference_speed"`
+	StartupPauseGraceSecs     float64 `toml:"startup_pause_grace_secs"`
+	RidingPauseGraceSecs      float64 `toml:"riding_pause_grace_secs"`
+	PauseEnterSpeed           float64 `toml:"pause_enter_speed"`
+	PauseExitSpeed            float64 `toml:"pause_exit_speed"`
+	ZeroDeadband              float64 `toml:"zero_deadband"`
+	AmbiguousZeroPolicy       string  `toml:"ambiguous_zero_policy"`
+	DistancePrecision         float64 `toml:"distance_precision"`
+	LatencyCompensation       float64 `toml:"latency_compensation"`
+	HoldDuringGapSecs         float64 `toml:"hold_during_gap_secs"`
+	MaxIntegrationStepSecs    float64 `toml:"max_integration_step_secs"`
+	MaxSpeedChangeRate        float64 `toml:"max_speed_change_rate"`
+	StrictCircumference       bool    `toml:"strict_circumference"`
+	CadenceToSpeedFactor      float64 `toml:"cadence_to_speed_factor"`
+	InterpolateBetweenSamples bool    `toml:"interpolate_between_samples"`
+}
+
+// CadenceConfig represents the cadence (crank revolutions per minute) configuration
+type CadenceConfig struct {
+	Enabled         bool `toml:"enabled"`
+	SmoothingWindow int  `toml:"smoothing_window"`
 }
 
 // VideoOSDConfig represents the on-screen display configuration
 type VideoOSDConfig struct {
 	DisplayCycleSpeed    bool `toml:"display_cycle_speed"`
 	DisplayPlaybackSpeed bool `toml:"display_playback_speed"`
+	DisplayCadence       bool `toml:"display_cadence"`
 	ShowOSD              bool
 }
 
@@ -63,12 +186,35 @@ type VideoConfig struct {
 	WindowScaleFactor float64        `toml:"window_scale_factor"`
 	UpdateIntervalSec float64        `toml:"update_interval_sec"`
 	SpeedMultiplier   float64        `toml:"speed_multiplier"`
+	MinPlaybackSpeed  float64        `toml:"min_playback_speed"`
+	MaxPlaybackSpeed  float64        `toml:"max_playback_speed"`
+	SpeedFilter       string         `toml:"speed_filter"`
+	ResumeRampSecs    float64        `toml:"resume_ramp_secs"`
+	FrozenTimeoutSecs float64        `toml:"frozen_timeout_secs"`
+	OnEnd             string         `toml:"on_end"`
+	PauseHotkey       string         `toml:"pause_hotkey"`
+	MarkerHotkey      string         `toml:"marker_hotkey"`
+	AudioOnly         bool           `toml:"audio_only"`
+	Headless          string         `toml:"headless"`
 	OnScreenDisplay   VideoOSDConfig `toml:"OSD"`
 }
 
+// TTSConfig represents the text-to-speech (TTS) speed announcement configuration
+type TTSConfig struct {
+	Enabled      bool   `toml:"enabled"`
+	Command      string `toml:"command"`
+	IntervalSecs int    `toml:"interval_secs"`
+}
+
 // LoadFile attempts to load the TOML configuration file from the specified path,
-// falling back to the default configuration directory if not found
+// falling back to the default configuration directory if not found. If the path is a
+// directory, its TOML configuration fragments are merged instead
 func LoadFile(filename string) (*Config, error) {
+
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		return loadDir(filename)
+	}
+
 	// Define configuration file paths
 	paths := []string{
 		filename,
@@ -104,6 +250,52 @@ func LoadFile(filename string) (*Config, error) {
 	return nil, lastErr
 }
 
+// loadDir loads and merges all TOML configuration fragments (*.toml) in the directory in lexical
+// order, with values in later fragments overriding those in earlier ones
+func loadDir(dir string) (*Config, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, errors.New("no TOML configuration files found in " + dir)
+	}
+
+	cfg := &Config{}
+	definedBy := make(map[string]string)
+
+	// Decode each fragment over the values of previous fragments
+	for _, path := range paths {
+		md, err := toml.DecodeFile(path, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config from %s: %w", path, err)
+		}
+
+		for _, key := range md.Keys() {
+
+			// Tables can be shared across fragments, so only values can conflict
+			if keyType := md.Type(key...); keyType == "Table" || keyType == "Hash" {
+				continue
+			}
+
+			if previous, ok := definedBy[key.String()]; ok {
+				log.Println("[APP] configuration key " + key.String() + " in " + path + " overrides the value in " + previous)
+			}
+
+			definedBy[key.String()] = path
+		}
+
+	}
+
+	// Validate merged configuration
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
 // validate performs validation on the configuration values
 func (c *Config) validate() error {
 
@@ -121,10 +313,18 @@ func (c *Config) validate() error {
 		return err
 	}
 
+	if err := c.Cadence.validate(); err != nil {
+		return err
+	}
+
 	if err := c.Video.validate(); err != nil {
 		return err
 	}
 
+	if err := c.TTS.validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -133,21 +333,150 @@ func (ac *AppConfig) validate() error {
 	// Validate log level
 	switch ac.LogLevel {
 	case logLevelDebug, logLevelInfo, logLevelWarn, logLevelError:
-		return nil
 	default:
 		return errors.New("invalid log level: " + ac.LogLevel)
 	}
 
+	// Confirm that display_smoothing_secs is not negative
+	if ac.DisplaySmoothingSecs < 0.0 {
+		return errors.New("display_smoothing_secs must not be negative")
+	}
+
+	// Confirm that max_run_secs is not negative
+	if ac.MaxRunSecs < 0 {
+		return errors.New("max_run_secs must not be negative")
+	}
+
+	// Confirm that stats_warmup_secs is not negative
+	if ac.StatsWarmupSecs < 0.0 {
+		return errors.New("stats_warmup_secs must not be negative")
+	}
+
+	// Confirm that history_max_samples is not negative
+	if ac.HistoryMaxSamples < 0 {
+		return errors.New("history_max_samples must not be negative")
+	}
+
+	// Validate shutdown order, defaulting to stopping the video first
+	switch ac.ShutdownOrder {
+	case "":
+		ac.ShutdownOrder = ShutdownVideoFirst
+	case ShutdownVideoFirst, ShutdownBLEFirst:
+	default:
+		return errors.New("invalid shutdown order: " + ac.ShutdownOrder)
+	}
+
+	return nil
 }
 
 // validate validates BLEConfig elements
 func (bc *BLEConfig) validate() error {
 
+	// Validate speed source, defaulting to the BLE sensor
+	switch bc.Source {
+	case "":
+		bc.Source = SourceBLE
+	case SourceBLE:
+	case SourceDemo:
+		return bc.Demo.validate()
+	default:
+		return errors.New("invalid speed source: " + bc.Source)
+	}
+
 	// Check if the sensor UUID is specified
-	if bc.SensorUUID == "" {
+	if strings.TrimSpace(bc.SensorUUID) == "" {
 		return errors.New("sensor UUID must be specified in configuration")
 	}
 
+	// Default the sensor label to its address
+	if bc.SensorLabel == "" {
+		bc.SensorLabel = bc.SensorUUID
+	}
+
+	// Validate CSC decoding quirks
+	if err := bc.Quirks.validate(); err != nil {
+		return err
+	}
+
+	// Confirm that discovery_retries is not negative
+	if bc.DiscoveryRetries < 0 {
+		return errors.New("discovery_retries must not be negative")
+	}
+
+	// Confirm that startup_budget_secs is not negative
+	if bc.StartupBudgetSecs < 0 {
+		return errors.New("startup_budget_secs must not be negative")
+	}
+
+	// Check that the (optional) scan service UUID is well formed
+	if bc.ScanServiceUUID != "" && !serviceUUIDPattern.MatchString(bc.ScanServiceUUID) {
+		return errors.New("invalid scan service UUID: " + bc.ScanServiceUUID)
+	}
+
+	// Confirm that fallback_to_sim_after is not negative, and that the fallback demo source is valid
+	if bc.FallbackToSimAfter < 0 {
+		return errors.New("fallback_to_sim_after must not be negative")
+	}
+
+	if bc.FallbackToSimAfter > 0 {
+		return bc.Demo.validate()
+	}
+
+	return nil
+}
+
+// SensorUUIDWarning returns a warning if the sensor UUID doesn't look like a BLE peripheral address
+// (so a scan would likely never find it), or an empty string if it looks valid
+func (bc *BLEConfig) SensorUUIDWarning() string {
+
+	if !sensorAddressPattern.MatchString(bc.SensorUUID) {
+		return "sensor_uuid of \"" + bc.SensorUUID + "\" doesn't look like a BLE peripheral address (e.g., \"11:22:33:44:55:66\"), so the sensor may never be found"
+	}
+
+	return ""
+}
+
+// validate validates DemoConfig elements
+func (dc *DemoConfig) validate() error {
+
+	// Validate demo speed profile
+	switch dc.Profile {
+	case DemoProfileSine, DemoProfileSawtooth:
+	default:
+		return errors.New("invalid demo speed profile: " + dc.Profile)
+	}
+
+	// Confirm that the speed range is valid
+	if dc.MinSpeed < 0.0 || dc.MaxSpeed <= dc.MinSpeed {
+		return errors.New("demo max_speed must be greater than min_speed, and min_speed must not be negative")
+	}
+
+	// Confirm that period_secs is >0
+	if dc.PeriodSecs <= 0 {
+		return errors.New("demo period_secs must be greater than 0")
+	}
+
+	return nil
+}
+
+// validate validates QuirksConfig elements
+func (qc *QuirksConfig) validate() error {
+
+	// Confirm that field offsets (if any) fall within a CSC measurement, after the flags byte
+	if qc.WheelRevsOffset < 0 || qc.WheelRevsOffset > maxQuirkFieldOffset ||
+		qc.WheelTimeOffset < 0 || qc.WheelTimeOffset > maxQuirkFieldOffset {
+		return errors.New("quirks wheel_revs_offset and wheel_time_offset must be between 0 (default) and " + strconv.Itoa(maxQuirkFieldOffset))
+	}
+
+	// Validate wheel event time resolution, defaulting to the CSC specification
+	if qc.WheelTimeResolution == 0 {
+		qc.WheelTimeResolution = DefaultWheelTimeResolution
+	}
+
+	if qc.WheelTimeResolution < 0 {
+		return errors.New("quirks wheel_time_resolution must be positive")
+	}
+
 	return nil
 }
 
@@ -157,11 +486,125 @@ func (sc *SpeedConfig) validate() error {
 	// Validate speed units
 	switch sc.SpeedUnits {
 	case SpeedUnitsKMH, SpeedUnitsMPH:
-		return nil
 	default:
 		return errors.New("invalid speed units: " + sc.SpeedUnits)
 	}
 
+	// Validate ambiguous zero speed policy, defaulting to reporting zero
+	switch sc.AmbiguousZeroPolicy {
+	case "":
+		sc.AmbiguousZeroPolicy = AmbiguousZeroReport
+	case AmbiguousZeroReport, AmbiguousZeroHold:
+	default:
+		return errors.New("invalid ambiguous_zero_policy: " + sc.AmbiguousZeroPolicy)
+	}
+
+	// Confirm that smoothing_window is at least 1 (no smoothing)
+	if sc.SmoothingWindow < 1 {
+		return errors.New("smoothing_window must be at least 1 (1 disables smoothing)")
+	}
+
+	// Confirm that invert_reference_speed is >0.0 when inverting speed
+	if sc.Invert && sc.InvertReferenceSpeed <= 0.0 {
+		return errors.New("invert_reference_speed must be greater than 0.0 when invert is enabled")
+	}
+
+	// Confirm that pause grace periods are not negative
+	if sc.StartupPauseGraceSecs < 0.0 || sc.RidingPauseGraceSecs < 0.0 {
+		return errors.New("startup_pause_grace_secs and riding_pause_grace_secs must not be negative")
+	}
+
+	// Confirm that the pause hysteresis speeds are not negative and not reversed
+	if sc.PauseEnterSpeed < 0.0 || sc.PauseExitSpeed < 0.0 {
+		return errors.New("pause_enter_speed and pause_exit_speed must not be negative")
+	}
+
+	if sc.PauseExitSpeed < sc.PauseEnterSpeed {
+		return errors.New("pause_exit_speed must not be less than pause_enter_speed")
+	}
+
+	// Confirm that zero_deadband is not negative
+	if sc.ZeroDeadband < 0.0 {
+		return errors.New("zero_deadband must not be negative")
+	}
+
+	// Confirm that cadence_to_speed_factor is not negative
+	if sc.CadenceToSpeedFactor < 0.0 {
+		return errors.New("cadence_to_speed_factor must not be negative")
+	}
+
+	// Confirm that distance_precision is not negative
+	if sc.DistancePrecision < 0.0 {
+		return errors.New("distance_precision must not be negative")
+	}
+
+	// Confirm that max_speed_change_rate is not negative
+	if sc.MaxSpeedChangeRate < 0.0 {
+		return errors.New("max_speed_change_rate must not be negative")
+	}
+
+	// Confirm that hold_during_gap_secs is not negative
+	if sc.HoldDuringGapSecs < 0.0 {
+		return errors.New("hold_during_gap_secs must not be negative")
+	}
+
+	// Confirm that max_integration_step_secs is not negative
+	if sc.MaxIntegrationStepSecs < 0.0 {
+		return errors.New("max_integration_step_secs must not be negative")
+	}
+
+	// Confirm that latency_compensation is between 0.0 and 1.0
+	if sc.LatencyCompensation < 0.0 || sc.LatencyCompensation > 1.0 {
+		return errors.New("latency_compensation must be between 0.0 and 1.0")
+	}
+
+	// Confirm that the wheel circumference is plausible when strict_circumference is enabled
+	if sc.StrictCircumference {
+
+		if warning := sc.WheelCircumferenceWarning(); warning != "" {
+			return errors.New(warning + " (disable strict_circumference to allow it)")
+		}
+
+	}
+
+	return nil
+}
+
+// WheelCircumferenceWarning returns a warning (with the likely unit error) if the wheel circumference
+// is outside the plausible range for a bicycle wheel, or an empty string if it is plausible
+func (sc *SpeedConfig) WheelCircumferenceWarning() string {
+	mm := float64(sc.WheelCircumferenceMM)
+
+	if mm >= minPlausibleWheelMM && mm <= maxPlausibleWheelMM {
+		return ""
+	}
+
+	warning := fmt.Sprintf("wheel_circumference_mm of %d is outside the typical bicycle range (%d-%dmm)",
+		sc.WheelCircumferenceMM, minPlausibleWheelMM, maxPlausibleWheelMM)
+
+	// Suggest the unit most likely entered by mistake, favoring inches when using mph
+	units := []struct {
+		name string
+		mm   float64
+	}{
+		{"centimeters", mmPerCentimeter},
+		{"inches", mmPerInch},
+		{"meters", mmPerMeter},
+	}
+
+	if sc.SpeedUnits == SpeedUnitsMPH {
+		units[0], units[1] = units[1], units[0]
+	}
+
+	for _, unit := range units {
+
+		if converted := mm * unit.mm; converted >= minPlausibleWheelMM && converted <= maxPlausibleWheelMM {
+			return warning + fmt.Sprintf(": was it entered in %s? (%d %s is %.0fmm)", unit.name, sc.WheelCircumferenceMM, unit.name, converted)
+		}
+
+	}
+
+	return warning
 }
 
 // validate validates VideoConfig elements
@@ -177,8 +620,155 @@ func (vc *VideoConfig) validate() error {
 		return errors.New("update_interval_sec must be greater than 0.0")
 	}
 
+	// Confirm that playback speed bounds are not negative and not reversed
+	if vc.MinPlaybackSpeed < 0.0 || vc.MaxPlaybackSpeed < 0.0 {
+		return errors.New("min_playback_speed and max_playback_speed must not be negative")
+	}
+
+	if vc.MaxPlaybackSpeed > 0.0 && vc.MinPlaybackSpeed > vc.MaxPlaybackSpeed {
+		return errors.New("min_playback_speed must not be greater than max_playback_speed")
+	}
+
+	// Confirm that resume_ramp_secs is not negative
+	if vc.ResumeRampSecs < 0.0 {
+		return errors.New("resume_ramp_secs must not be negative")
+	}
+
+	// Confirm that frozen_timeout_secs is not negative
+	if vc.FrozenTimeoutSecs < 0.0 {
+		return errors.New("frozen_timeout_secs must not be negative")
+	}
+
+	// Validate end of video behavior, defaulting to stopping the application
+	switch vc.OnEnd {
+	case "":
+		vc.OnEnd = OnEndStop
+	case OnEndStop, OnEndHold, OnEndLoop:
+	default:
+		return errors.New("invalid on_end behavior: " + vc.OnEnd)
+	}
+
+	// Validate the behavior without a display, defaulting to failing with an explanation
+	switch vc.Headless {
+	case "":
+		vc.Headless = HeadlessFail
+	case HeadlessFail, HeadlessAudioOnly:
+	default:
+		return errors.New("invalid headless behavior: " + vc.Headless)
+	}
+
+	// Check that the (optional) pause hotkey is a single MPV key name
+	if strings.ContainsAny(vc.PauseHotkey, " \t\"';#") {
+		return errors.New("invalid pause_hotkey: " + vc.PauseHotkey)
+	}
+
+	// Check that the (optional) marker hotkey is a single MPV key name, distinct from the pause hotkey
+	if strings.ContainsAny(vc.MarkerHotkey, " \t\"';#") {
+		return errors.New("invalid marker_hotkey: " + vc.MarkerHotkey)
+	}
+
+	if vc.MarkerHotkey != "" && vc.MarkerHotkey == vc.PauseHotkey {
+		return errors.New("marker_hotkey must differ from pause_hotkey: " + vc.MarkerHotkey)
+	}
+
+	// Check that the speed filter (if any) uses safe filter syntax and known placeholders
+	if err := validateSpeedFilter(vc.SpeedFilter); err != nil {
+		return err
+	}
+
 	// Check if at least one OSD display flag is set
-	vc.OnScreenDisplay.ShowOSD = (vc.OnScreenDisplay.DisplayCycleSpeed || vc.OnScreenDisplay.DisplayPlaybackSpeed)
+	vc.OnScreenDisplay.ShowOSD = (vc.OnScreenDisplay.DisplayCycleSpeed || vc.OnScreenDisplay.DisplayPlaybackSpeed ||
+		vc.OnScreenDisplay.DisplayCadence) && !vc.AudioOnly
+
+	return nil
+}
+
+// AudioOnlyWarning returns a warning listing the configured video features that are ignored in
+// audio-only mode, or an empty string if there are none
+func (vc *VideoConfig) AudioOnlyWarning() string {
+
+	if !vc.AudioOnly {
+		return ""
+	}
+
+	var ignored []string
+
+	if vc.OnScreenDisplay.DisplayCycleSpeed || vc.OnScreenDisplay.DisplayPlaybackSpeed || vc.OnScreenDisplay.DisplayCadence {
+		ignored = append(ignored, "the OSD")
+	}
+
+	if vc.SpeedFilter != "" {
+		ignored = append(ignored, "speed_filter")
+	}
+
+	if vc.PauseHotkey != "" {
+		ignored = append(ignored, "pause_hotkey")
+	}
+
+	if vc.MarkerHotkey != "" {
+		ignored = append(ignored, "marker_hotkey")
+	}
+
+	if len(ignored) == 0 {
+		return ""
+	}
+
+	return "audio_only has no video output, so ignoring " + strings.Join(ignored, ", ")
+}
+
+// validate validates CadenceConfig elements
+func (cc *CadenceConfig) validate() error {
+
+	// Nothing to validate if cadence is disabled
+	if !cc.Enabled {
+		return nil
+	}
+
+	// Confirm that smoothing_window is at least 1 (no smoothing)
+	if cc.SmoothingWindow < 1 {
+		return errors.New("cadence smoothing_window must be at least 1 (1 disables smoothing)")
+	}
+
+	return nil
+}
+
+// validate validates TTSConfig elements
+func (tc *TTSConfig) validate() error {
+
+	// Nothing to validate if speed announcements are disabled
+	if !tc.Enabled {
+		return nil
+	}
+
+	// Check if the TTS command is specified
+	if tc.Command == "" {
+		return errors.New("TTS command must be specified when TTS is enabled")
+	}
+
+	// Confirm that interval_secs is >0
+	if tc.IntervalSecs <= 0 {
+		return errors.New("TTS interval_secs must be greater than 0")
+	}
+
+	return nil
+}
+
+// validateSpeedFilter validates the video speed filter template
+func validateSpeedFilter(filter string) error {
+
+	if filter == "" {
+		return nil
+	}
+
+	if !speedFilterPattern.MatchString(filter) {
+		return errors.New("invalid speed_filter (unsupported characters): " + filter)
+	}
+
+	// Confirm that only the supported placeholders are used
+	unknown := strings.NewReplacer(SpeedFilterSpeed, "", SpeedFilterRate, "").Replace(filter)
+	if strings.ContainsAny(unknown, "{}") {
+		return errors.New("invalid speed_filter (unknown placeholder): " + filter)
+	}
 
 	return nil
 }
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -157,6 +159,60 @@ func TestLoadFile(t *testing.T) {
 
 }
 
+// TestLoadFileDirectory tests merging a directory of configuration fragments in lexical order
+func TestLoadFileDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	// Fragments are written out of lexical order to confirm that load order is by filename
+	fragments := map[string]string{
+		"20-override.toml": `
+			[speed]
+			speed_units = "mph"
+
+			[video]
+			speed_multiplier = 0.5
+		`,
+		"10-base.toml": generateConfigTOML(true),
+		"30-override.toml": `
+			[video]
+			speed_multiplier = 0.8
+		`,
+		"notes.txt": `not a configuration fragment`,
+	}
+
+	for name, content := range fragments {
+
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write fragment file: %v", err)
+		}
+
+	}
+
+	cfg, err := LoadFile(dir)
+	if err != nil {
+		t.Fatalf("LoadFile() error = %v", err)
+	}
+
+	// Later fragments override earlier ones, and untouched values are kept
+	if cfg.Speed.SpeedUnits != SpeedUnitsMPH {
+		t.Errorf("SpeedUnits = %q, want %q", cfg.Speed.SpeedUnits, SpeedUnitsMPH)
+	}
+
+	if cfg.Video.SpeedMultiplier != 0.8 {
+		t.Errorf("SpeedMultiplier = %v, want 0.8", cfg.Video.SpeedMultiplier)
+	}
+
+	if cfg.BLE.SensorUUID != td.sensorUUID {
+		t.Errorf("SensorUUID = %q, want %q", cfg.BLE.SensorUUID, td.sensorUUID)
+	}
+
+	// An empty directory is an error
+	if _, err := LoadFile(t.TempDir()); err == nil {
+		t.Error("LoadFile() of an empty directory should return an error")
+	}
+
+}
+
 // TestValidateAppConfig tests AppConfig validation
 func TestValidateAppConfig(t *testing.T) {
 	// Create tests
@@ -171,6 +227,51 @@ func TestValidateAppConfig(t *testing.T) {
 			input:   AppConfig{LogLevel: td.invalidLevel},
 			wantErr: true,
 		},
+		{
+			name:    "valid display smoothing",
+			input:   AppConfig{LogLevel: td.logLevel, DisplaySmoothingSecs: 3.0},
+			wantErr: false,
+		},
+		{
+			name:    "negative display smoothing",
+			input:   AppConfig{LogLevel: td.logLevel, DisplaySmoothingSecs: -1.0},
+			wantErr: true,
+		},
+		{
+			name:    "valid max run time",
+			input:   AppConfig{LogLevel: td.logLevel, MaxRunSecs: 3600},
+			wantErr: false,
+		},
+		{
+			name:    "negative max run time",
+			input:   AppConfig{LogLevel: td.logLevel, MaxRunSecs: -1},
+			wantErr: true,
+		},
+		{
+			name:    "valid stats warmup",
+			input:   AppConfig{LogLevel: td.logLevel, StatsWarmupSecs: 5.0},
+			wantErr: false,
+		},
+		{
+			name:    "negative stats warmup",
+			input:   AppConfig{LogLevel: td.logLevel, StatsWarmupSecs: -1.0},
+			wantErr: true,
+		},
+		{
+			name:    "valid shutdown order",
+			input:   AppConfig{LogLevel: td.logLevel, ShutdownOrder: ShutdownBLEFirst},
+			wantErr: false,
+		},
+		{
+			name:    "invalid shutdown order",
+			input:   AppConfig{LogLevel: td.logLevel, ShutdownOrder: "together"},
+			wantErr: true,
+		},
+		{
+			name:    "negative history max samples",
+			input:   AppConfig{LogLevel: td.logLevel, HistoryMaxSamples: -1},
+			wantErr: true,
+		},
 	}
 
 	// Run tests
@@ -189,6 +290,13 @@ func TestValidateBLEConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "blank sensor UUID",
+			input: BLEConfig{
+				SensorUUID: "   ",
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid BLE config",
 			input: BLEConfig{
@@ -197,6 +305,134 @@ func TestValidateBLEConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid demo source config",
+			input: BLEConfig{
+				Source: SourceDemo,
+				Demo: DemoConfig{
+					Profile:    DemoProfileSine,
+					MinSpeed:   5.0,
+					MaxSpeed:   25.0,
+					PeriodSecs: 120,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid demo source config",
+			input: BLEConfig{
+				Source: SourceDemo,
+				Demo: DemoConfig{
+					Profile:    "square",
+					MinSpeed:   25.0,
+					MaxSpeed:   5.0,
+					PeriodSecs: 0,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid simulation fallback config",
+			input: BLEConfig{
+				SensorUUID:         td.sensorUUID,
+				FallbackToSimAfter: 3,
+				Demo: DemoConfig{
+					Profile:    DemoProfileSine,
+					MinSpeed:   5.0,
+					MaxSpeed:   25.0,
+					PeriodSecs: 120,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "simulation fallback without demo config",
+			input: BLEConfig{
+				SensorUUID:         td.sensorUUID,
+				FallbackToSimAfter: 3,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative simulation fallback",
+			input: BLEConfig{
+				SensorUUID:         td.sensorUUID,
+				FallbackToSimAfter: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid scan service UUID",
+			input: BLEConfig{
+				SensorUUID:      td.sensorUUID,
+				ScanServiceUUID: "1816",
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid 128-bit scan service UUID",
+			input: BLEConfig{
+				SensorUUID:      td.sensorUUID,
+				ScanServiceUUID: "00001816-0000-1000-8000-00805f9b34fb",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid scan service UUID",
+			input: BLEConfig{
+				SensorUUID:      td.sensorUUID,
+				ScanServiceUUID: "0x1816",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid decoding quirks",
+			input: BLEConfig{
+				SensorUUID: td.sensorUUID,
+				Quirks:     QuirksConfig{ForceWheelData: true, WheelRevsOffset: 3, WheelTimeOffset: 1},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid decoding quirk offset",
+			input: BLEConfig{
+				SensorUUID: td.sensorUUID,
+				Quirks:     QuirksConfig{WheelRevsOffset: 64},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative wheel time resolution",
+			input: BLEConfig{
+				SensorUUID: td.sensorUUID,
+				Quirks:     QuirksConfig{WheelTimeResolution: -1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative discovery retries",
+			input: BLEConfig{
+				SensorUUID:       td.sensorUUID,
+				DiscoveryRetries: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative startup budget",
+			input: BLEConfig{
+				SensorUUID:        td.sensorUUID,
+				StartupBudgetSecs: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid speed source",
+			input: BLEConfig{
+				SensorUUID: td.sensorUUID,
+				Source:     "gps",
+			},
+			wantErr: true,
+		},
 	}
 
 	// Run tests
@@ -217,6 +453,205 @@ func TestValidateSpeedConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid inverted speed config",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				Invert:               true,
+				InvertReferenceSpeed: 20.0,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid inverted speed config",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				Invert:               true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "no smoothing",
+			input: SpeedConfig{
+				SmoothingWindow:      1,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+			},
+			wantErr: false,
+		},
+		{
+			name: "zero smoothing window",
+			input: SpeedConfig{
+				SmoothingWindow:      0,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+			},
+			wantErr: true,
+		},
+		{
+			name: "latency compensation out of range",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				LatencyCompensation:  1.5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative speed change rate",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				MaxSpeedChangeRate:   -5.0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid pause hysteresis",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				PauseEnterSpeed:      2.0,
+				PauseExitSpeed:       4.0,
+			},
+			wantErr: false,
+		},
+		{
+			name: "reversed pause hysteresis",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				PauseEnterSpeed:      4.0,
+				PauseExitSpeed:       2.0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid ambiguous zero policy",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				AmbiguousZeroPolicy:  "ignore",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative distance precision",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				DistancePrecision:    -0.1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative zero deadband",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				ZeroDeadband:         -0.5,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative cadence to speed factor",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				CadenceToSpeedFactor: -1.0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative gap hold",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2000,
+				SpeedUnits:           SpeedUnitsKMH,
+				HoldDuringGapSecs:    -2.0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative max integration step",
+			input: SpeedConfig{
+				SmoothingWindow:        5,
+				SpeedThreshold:         10.0,
+				WheelCircumferenceMM:   2000,
+				SpeedUnits:             SpeedUnitsKMH,
+				MaxIntegrationStepSecs: -1.0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative pause grace",
+			input: SpeedConfig{
+				SmoothingWindow:       5,
+				SpeedThreshold:        10.0,
+				WheelCircumferenceMM:  2000,
+				SpeedUnits:            SpeedUnitsKMH,
+				StartupPauseGraceSecs: 5.0,
+				RidingPauseGraceSecs:  -1.0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "implausible circumference warns only",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2,
+				SpeedUnits:           SpeedUnitsKMH,
+			},
+			wantErr: false,
+		},
+		{
+			name: "implausible circumference with strict circumference",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2,
+				SpeedUnits:           SpeedUnitsKMH,
+				StrictCircumference:  true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "plausible circumference with strict circumference",
+			input: SpeedConfig{
+				SmoothingWindow:      5,
+				SpeedThreshold:       10.0,
+				WheelCircumferenceMM: 2105,
+				SpeedUnits:           SpeedUnitsKMH,
+				StrictCircumference:  true,
+			},
+			wantErr: false,
+		},
 		{
 			name: "invalid speed config",
 			input: SpeedConfig{
@@ -233,6 +668,103 @@ func TestValidateSpeedConfig(t *testing.T) {
 	runValidationTests(t, tests)
 }
 
+// TestSensorLabelDefault tests that the sensor label defaults to the sensor address
+func TestSensorLabelDefault(t *testing.T) {
+	bc := BLEConfig{SensorUUID: "F1:42:D8:DE:35:16"}
+
+	if err := bc.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+
+	if bc.SensorLabel != bc.SensorUUID {
+		t.Errorf("SensorLabel = %q, want %q", bc.SensorLabel, bc.SensorUUID)
+	}
+
+	bc = BLEConfig{SensorUUID: "F1:42:D8:DE:35:16", SensorLabel: "rear wheel"}
+
+	if err := bc.validate(); err != nil || bc.SensorLabel != "rear wheel" {
+		t.Errorf("validate() = %v, SensorLabel = %q, want rear wheel", err, bc.SensorLabel)
+	}
+
+}
+
+// TestSensorUUIDWarning tests warnings for sensor UUIDs that don't look like BLE peripheral addresses
+func TestSensorUUIDWarning(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name        string
+		sensorUUID  string
+		wantWarning bool
+	}{
+		{"valid MAC address", "F1:42:D8:DE:35:16", false},
+		{"valid peripheral UUID", "6FA1B2C3-1234-4CDE-8F00-0123456789AB", false},
+		{"lowercase MAC address", "f1:42:d8:de:35:16", false},
+		{"MAC address with dashes", "F1-42-D8-DE-35-16", false},
+		{"MAC address without separators", "F142D8DE3516", false},
+		{"MAC address with mixed separators", "F1:42-D8:DE-35:16", true},
+		{"truncated MAC address", "F1:42:D8:DE:35", true},
+		{"not an address", "my speed sensor", true},
+		{"empty", "", true},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := BLEConfig{SensorUUID: tt.sensorUUID}
+
+			if warning := bc.SensorUUIDWarning(); (warning != "") != tt.wantWarning {
+				t.Errorf("SensorUUIDWarning() = %q, wantWarning %v", warning, tt.wantWarning)
+			}
+
+		})
+	}
+
+}
+
+// TestWheelCircumferenceWarning tests warnings for implausible wheel circumferences
+func TestWheelCircumferenceWarning(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name          string
+		circumference int
+		speedUnits    string
+		wantWarning   bool
+		wantUnit      string
+	}{
+		{"plausible road wheel", 2105, SpeedUnitsKMH, false, ""},
+		{"plausible small wheel", 1020, SpeedUnitsMPH, false, ""},
+		{"entered in centimeters", 210, SpeedUnitsKMH, true, "centimeters"},
+		{"entered in inches", 85, SpeedUnitsMPH, true, "inches"},
+		{"ambiguous value favors centimeters with km/h", 92, SpeedUnitsKMH, true, "centimeters"},
+		{"ambiguous value favors inches with mph", 92, SpeedUnitsMPH, true, "inches"},
+		{"entered in meters", 2, SpeedUnitsKMH, true, "meters"},
+		{"too small for any unit", 5, SpeedUnitsKMH, true, ""},
+		{"too large", 21050, SpeedUnitsKMH, true, ""},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := SpeedConfig{WheelCircumferenceMM: tt.circumference, SpeedUnits: tt.speedUnits}
+			warning := sc.WheelCircumferenceWarning()
+
+			if (warning != "") != tt.wantWarning {
+				t.Fatalf("WheelCircumferenceWarning() = %q, wantWarning %v", warning, tt.wantWarning)
+			}
+
+			if tt.wantUnit != "" && !strings.Contains(warning, "entered in "+tt.wantUnit) {
+				t.Errorf("WheelCircumferenceWarning() = %q, want suggestion of %s", warning, tt.wantUnit)
+			}
+
+			if tt.wantWarning && tt.wantUnit == "" && strings.Contains(warning, "entered in") {
+				t.Errorf("WheelCircumferenceWarning() = %q, want no unit suggestion", warning)
+			}
+
+		})
+	}
+
+}
+
 // TestValidateVideoConfig tests VideoConfig validation
 func TestValidateVideoConfig(t *testing.T) {
 
@@ -251,6 +783,108 @@ func TestValidateVideoConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid end of video behavior",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				OnEnd:             OnEndHold,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid end of video behavior",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				OnEnd:             "rewind",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative frozen timeout",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				FrozenTimeoutSecs: -1.0,
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid headless behavior",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				Headless:          "noop",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid pause hotkey",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				PauseHotkey:       "SPACE",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid pause hotkey",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				PauseHotkey:       "p quit",
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid marker hotkey",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				PauseHotkey:       "p",
+				MarkerHotkey:      "m",
+			},
+			wantErr: false,
+		},
+		{
+			name: "marker hotkey same as pause hotkey",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				PauseHotkey:       "p",
+				MarkerHotkey:      "p",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid playback speed bounds",
+			input: VideoConfig{
+				FilePath:          td.filename,
+				WindowScaleFactor: 1.0,
+				UpdateIntervalSec: 1,
+				SpeedMultiplier:   1.0,
+				MinPlaybackSpeed:  2.0,
+				MaxPlaybackSpeed:  1.0,
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid video config",
 			input: VideoConfig{
@@ -289,3 +923,129 @@ func TestValidateVideoConfig(t *testing.T) {
 	}
 
 }
+
+// TestAudioOnly tests that audio-only mode disables the OSD, warning about ignored video features
+func TestAudioOnly(t *testing.T) {
+	vc := VideoConfig{
+		FilePath:          td.filename,
+		WindowScaleFactor: 1.0,
+		UpdateIntervalSec: 1,
+		SpeedMultiplier:   1.0,
+		AudioOnly:         true,
+		PauseHotkey:       "p",
+		OnScreenDisplay:   VideoOSDConfig{DisplayCycleSpeed: true},
+	}
+
+	if err := vc.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+
+	if vc.OnScreenDisplay.ShowOSD {
+		t.Error("ShowOSD = true in audio-only mode, want false")
+	}
+
+	if warning := vc.AudioOnlyWarning(); !strings.Contains(warning, "the OSD") || !strings.Contains(warning, "pause_hotkey") {
+		t.Errorf("AudioOnlyWarning() = %q, want the OSD and pause_hotkey ignored", warning)
+	}
+
+	// Without any video features configured, there's nothing to warn about
+	vc = VideoConfig{AudioOnly: true}
+
+	if warning := vc.AudioOnlyWarning(); warning != "" {
+		t.Errorf("AudioOnlyWarning() = %q, want no warning", warning)
+	}
+
+}
+
+// TestValidateCadenceConfig tests CadenceConfig validation
+func TestValidateCadenceConfig(t *testing.T) {
+	// Create tests
+	tests := []testConfig[CadenceConfig]{
+		{
+			name:    "disabled cadence config",
+			input:   CadenceConfig{},
+			wantErr: false,
+		},
+		{
+			name: "valid cadence config",
+			input: CadenceConfig{
+				Enabled:         true,
+				SmoothingWindow: 3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid cadence smoothing window",
+			input: CadenceConfig{
+				Enabled:         true,
+				SmoothingWindow: 0,
+			},
+			wantErr: true,
+		},
+	}
+
+	// Run tests
+	runValidationTests(t, tests)
+}
+
+// TestValidateTTSConfig tests TTSConfig validation
+func TestValidateTTSConfig(t *testing.T) {
+	// Create tests
+	tests := []testConfig[TTSConfig]{
+		{
+			name:    "disabled TTS config",
+			input:   TTSConfig{},
+			wantErr: false,
+		},
+		{
+			name: "valid TTS config",
+			input: TTSConfig{
+				Enabled:      true,
+				Command:      "espeak",
+				IntervalSecs: 30,
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid TTS config",
+			input: TTSConfig{
+				Enabled:      true,
+				Command:      "",
+				IntervalSecs: 0,
+			},
+			wantErr: true,
+		},
+	}
+
+	// Run tests
+	runValidationTests(t, tests)
+}
+
+// TestValidateSpeedFilter tests video speed filter validation
+func TestValidateSpeedFilter(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name    string
+		filter  string
+		wantErr bool
+	}{
+		{"no filter", "", false},
+		{"rate placeholder", "gblur=sigma={rate}", false},
+		{"speed placeholder in lavfi graph", "lavfi=[boxblur=luma_radius={speed}:luma_power=1]", false},
+		{"unknown placeholder", "gblur=sigma={cadence}", true},
+		{"unsafe characters", "gblur=sigma=1; quit", true},
+		{"quoted filter", "\"gblur=sigma={rate}\"", true},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if err := validateSpeedFilter(tt.filter); (err != nil) != tt.wantErr {
+				t.Errorf("validateSpeedFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+		})
+	}
+
+}
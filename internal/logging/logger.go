@@ -12,6 +12,9 @@ import (
 // logger is the global logger
 var logger *slog.Logger
 
+// lifecycleEnabled enables the logging of goroutine and adapter lifecycle transitions
+var lifecycleEnabled bool
+
 // ExitFunc represents the exit function (used for testing)
 var ExitFunc = os.Exit
 
@@ -30,6 +33,7 @@ const (
 	BLE   ComponentType = "[BLE]"
 	SPEED ComponentType = "[SPEED]"
 	VIDEO ComponentType = "[VIDEO]"
+	TTS   ComponentType = "[TTS]"
 )
 
 // Color constants
@@ -74,6 +78,22 @@ func Debug(first interface{}, args ...interface{}) {
 	logWithOptionalComponent(context.Background(), slog.LevelDebug, first, args...)
 }
 
+// EnableLifecycle enables (or disables) the logging of lifecycle transitions
+func EnableLifecycle(enabled bool) {
+	lifecycleEnabled = enabled
+}
+
+// Lifecycle logs a goroutine or adapter lifecycle transition (e.g., to diagnose shutdown hangs) as
+// a debug message, if lifecycle logging is enabled
+func Lifecycle(component ComponentType, message string) {
+
+	if !lifecycleEnabled {
+		return
+	}
+
+	logWithOptionalComponent(context.Background(), slog.LevelDebug, component, "lifecycle: "+message)
+}
+
 // Fatal logs a fatal message
 func Fatal(first interface{}, args ...interface{}) {
 	logWithOptionalComponent(context.Background(), LevelFatal, first, args...)
@@ -170,6 +170,28 @@ func TestLogLevels(t *testing.T) {
 
 }
 
+// TestLifecycle tests that lifecycle transitions are logged only when enabled
+func TestLifecycle(t *testing.T) {
+	buf, testLogger := setupTest()
+	logger = testLogger
+
+	Lifecycle(BLE, "adapter scan started")
+
+	if buf.Len() != 0 {
+		t.Errorf("got %q with lifecycle logging disabled, want no output", buf.String())
+	}
+
+	EnableLifecycle(true)
+	defer EnableLifecycle(false)
+
+	Lifecycle(BLE, "adapter scan started")
+
+	if output := buf.String(); !strings.Contains(output, "[DEBUG]") || !strings.Contains(output, "[BLE] lifecycle: adapter scan started") {
+		t.Errorf("got %q, want a BLE lifecycle debug message", output)
+	}
+
+}
+
 func TestFatal(t *testing.T) {
 	buf, testLogger := setupTest()
 	logger = testLogger
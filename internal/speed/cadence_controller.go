@@ -0,0 +1,83 @@
+package speed
+
+import (
+	"container/ring"
+	"math"
+	"strconv"
+	"sync"
+
+	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
+)
+
+// CadenceController manages cadence measurements (in crank revolutions per minute) with its own
+// smoothing, independent of speed smoothing
+type CadenceController struct {
+	cadences       *ring.Ring
+	window         int
+	cadence        float64
+	averageCadence float64
+}
+
+// cadenceMutex manages concurrent access to CadenceController
+var cadenceMutex sync.RWMutex
+
+// NewCadenceController creates a new cadence controller with a specified window size
+func NewCadenceController(window int) *CadenceController {
+	r := ring.New(window)
+
+	// Initialize ring with zero values
+	for i := 0; i < window; i++ {
+		r.Value = float64(0)
+		r = r.Next()
+	}
+
+	return &CadenceController{
+		cadences: r,
+		window:   window,
+	}
+}
+
+// Cadence returns the most recent (unsmoothed) cadence measurement
+func (c *CadenceController) Cadence() float64 {
+	cadenceMutex.RLock()
+	defer cadenceMutex.RUnlock()
+
+	return c.cadence
+}
+
+// AverageCadence returns the smoothed (moving average) cadence measurement
+func (c *CadenceController) AverageCadence() float64 {
+	cadenceMutex.RLock()
+	defer cadenceMutex.RUnlock()
+
+	return c.averageCadence
+}
+
+// UpdateCadence updates the current cadence measurement and calculates a smoothed average
+func (c *CadenceController) UpdateCadence(cadence float64) {
+
+	// Reject invalid cadences, which would otherwise poison the smoothing buffer indefinitely
+	if math.IsNaN(cadence) || math.IsInf(cadence, 0) || cadence < 0 {
+		logger.Warn(logger.SPEED, "ignoring invalid cadence measurement: "+strconv.FormatFloat(cadence, 'f', 2, 64))
+		return
+	}
+
+	cadenceMutex.Lock()
+	defer cadenceMutex.Unlock()
+
+	c.cadence = cadence
+	c.cadences.Value = cadence
+	c.cadences = c.cadences.Next()
+
+	// Calculate smoothed cadence
+	sum := float64(0)
+	c.cadences.Do(func(x interface{}) {
+
+		if x != nil {
+			sum += x.(float64)
+		}
+
+	})
+
+	c.averageCadence = sum / float64(c.window)
+}
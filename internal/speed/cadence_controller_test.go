@@ -0,0 +1,62 @@
+package speed
+
+import (
+	"math"
+	"testing"
+)
+
+// TestCadenceController tests the Cadence and AverageCadence getters of CadenceController
+func TestCadenceController(t *testing.T) {
+	controller := NewCadenceController(4)
+
+	for _, cadence := range []float64{80, 84, 88, 92} {
+		controller.UpdateCadence(cadence)
+	}
+
+	if got := controller.Cadence(); got != 92 {
+		t.Errorf("Cadence() = %f, want 92", got)
+	}
+
+	if got := controller.AverageCadence(); got != 86 {
+		t.Errorf("AverageCadence() = %f, want 86", got)
+	}
+
+	// Invalid cadences are ignored
+	controller.UpdateCadence(math.NaN())
+	controller.UpdateCadence(-10)
+
+	if got := controller.AverageCadence(); got != 86 {
+		t.Errorf("AverageCadence() = %f after invalid cadences, want 86", got)
+	}
+
+}
+
+// TestCadenceSmoothingIndependent tests that cadence smoothing is independent of speed smoothing
+func TestCadenceSmoothingIndependent(t *testing.T) {
+	speedController := NewSpeedController(td.window)
+	cadenceController := NewCadenceController(1)
+
+	for _, speed := range td.speeds {
+		speedController.UpdateSpeed(speed)
+	}
+
+	cadenceController.UpdateCadence(90)
+
+	// Cadence uses its own (here, unsmoothed) window
+	if got := cadenceController.AverageCadence(); got != 90 {
+		t.Errorf("AverageCadence() = %f, want 90", got)
+	}
+
+	// Speed smoothing is unaffected by cadence updates
+	if got := speedController.GetSmoothedSpeed(); got != td.expectedSpeed {
+		t.Errorf("GetSmoothedSpeed() = %f, want %f", got, td.expectedSpeed)
+	}
+
+	// Speed updates don't feed the cadence
+	speedController.UpdateSpeed(20.0)
+
+	if got := cadenceController.Cadence(); got != 90 {
+		t.Errorf("Cadence() = %f after speed update, want 90", got)
+	}
+
+}
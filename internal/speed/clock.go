@@ -0,0 +1,58 @@
+package speed
+
+import "time"
+
+// clock is a source of wall-clock time (which can jump, e.g., on NTP adjustments) and monotonic
+// time (which never jumps)
+type clock interface {
+	wallNow() time.Time
+	monotonic() time.Duration
+}
+
+// systemClock reads the system clock, measuring monotonic time from its creation
+type systemClock struct {
+	origin time.Time
+}
+
+// newSystemClock creates a new system clock
+func newSystemClock() systemClock {
+	return systemClock{origin: time.Now()}
+}
+
+// wallNow returns the current wall-clock time (without a monotonic reading)
+func (c systemClock) wallNow() time.Time {
+	return time.Now().Round(0)
+}
+
+// monotonic returns the monotonic time elapsed since the clock was created
+func (c systemClock) monotonic() time.Duration {
+	return time.Since(c.origin)
+}
+
+// eventClock stamps speed events with a wall-clock start reference plus the monotonic time elapsed
+// since then, so timestamps stay meaningful while intervals between events can't jump
+type eventClock struct {
+	source    clock
+	startWall time.Time
+	startMono time.Duration
+}
+
+// newEventClock creates a new event clock, taking its wall-clock start reference from the source
+func newEventClock(source clock) eventClock {
+	return eventClock{
+		source:    source,
+		startWall: source.wallNow(),
+		startMono: source.monotonic(),
+	}
+}
+
+// now returns the timestamp of an event happening now (from the system clock if the event clock
+// wasn't created with a source)
+func (c eventClock) now() time.Time {
+
+	if c.source == nil {
+		return time.Now()
+	}
+
+	return c.startWall.Add(c.source.monotonic() - c.startMono)
+}
@@ -0,0 +1,84 @@
+package speed
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock whose wall-clock and monotonic times are set by the test
+type fakeClock struct {
+	wall time.Time
+	mono time.Duration
+}
+
+func (c *fakeClock) wallNow() time.Time       { return c.wall }
+func (c *fakeClock) monotonic() time.Duration { return c.mono }
+
+// advance moves both wall-clock and monotonic time forward
+func (c *fakeClock) advance(d time.Duration) {
+	c.wall = c.wall.Add(d)
+	c.mono += d
+}
+
+// TestEventClock tests that event timestamps follow monotonic time from the wall-clock start
+func TestEventClock(t *testing.T) {
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	fake := &fakeClock{wall: start, mono: 5 * time.Second}
+	events := newEventClock(fake)
+
+	fake.advance(10 * time.Second)
+	fake.wall = fake.wall.Add(-time.Hour) // wall-clock jump (e.g., an NTP adjustment)
+	fake.mono += 10 * time.Second
+
+	if got, want := events.now(), start.Add(20*time.Second); !got.Equal(want) {
+		t.Errorf("now() = %v, want %v", got, want)
+	}
+
+}
+
+// TestWallClockJump tests that ride interval math is unaffected by a wall-clock jump
+func TestWallClockJump(t *testing.T) {
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	fake := &fakeClock{wall: start}
+	controller := newSpeedController(1, fake)
+	controller.SetGapHold(time.Minute)
+
+	// Ride at 10 m/s for 30s, with the wall clock jumping back an hour, then forward two hours
+	for i := 0; i < 3; i++ {
+		controller.UpdateSpeed(10.0)
+		fake.advance(10 * time.Second)
+
+		switch i {
+		case 0:
+			fake.wall = fake.wall.Add(-time.Hour)
+		case 1:
+			fake.wall = fake.wall.Add(2 * time.Hour)
+		}
+
+	}
+
+	// The forward jump isn't mistaken for a gap in speed measurements
+	if got := controller.AppliedSpeed(); got != 10.0 {
+		t.Errorf("AppliedSpeed() = %f after wall-clock jump, want 10", got)
+	}
+
+	controller.UpdateSpeed(10.0)
+	summary := controller.GetRideSummary()
+
+	if !summary.StartTime.Equal(start) {
+		t.Errorf("StartTime = %v, want %v", summary.StartTime, start)
+	}
+
+	if summary.ElapsedTime != 30*time.Second {
+		t.Errorf("ElapsedTime = %v, want 30s", summary.ElapsedTime)
+	}
+
+	if summary.MovingTime != 30*time.Second {
+		t.Errorf("MovingTime = %v, want 30s", summary.MovingTime)
+	}
+
+	if summary.Distance != 300.0 {
+		t.Errorf("Distance = %f, want 300", summary.Distance)
+	}
+
+}
@@ -0,0 +1,96 @@
+package speed
+
+import (
+	"slices"
+	"time"
+)
+
+// defaultSpeedHistory is the default for the most speed samples kept for the ride summary: once
+// full, adjacent samples are averaged together, so the history always spans the whole ride
+const defaultSpeedHistory = 256
+
+// RideSummary represents the statistics of a completed ride
+type RideSummary struct {
+	StartTime    time.Time // wall-clock time of the first speed measurement
+	Distance     float64   // meters
+	MovingTime   time.Duration
+	ElapsedTime  time.Duration
+	AverageSpeed float64 // average moving speed, in meters per second
+	MaxSpeed     float64 // meters per second
+	Glitches     int     // speed changes faster than the maximum change rate
+}
+
+// GetRideSummary returns the statistics of the ride so far
+func (t *SpeedController) GetRideSummary() RideSummary {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	summary := RideSummary{
+		Distance:   t.distance(),
+		MovingTime: t.movingTime,
+		MaxSpeed:   t.maxSpeed,
+		Glitches:   t.glitches,
+	}
+
+	if !t.rideStart.IsZero() {
+		summary.StartTime = t.rideStart
+		summary.ElapsedTime = t.lastUpdate.Sub(t.rideStart)
+	}
+
+	if t.movingTime > 0 {
+		summary.AverageSpeed = t.speedTimeSum / t.movingTime.Seconds()
+	}
+
+	return summary
+}
+
+// GetSpeedHistory returns the speeds (in meters per second) sampled evenly over the ride so far
+func (t *SpeedController) GetSpeedHistory() []float64 {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return slices.Clone(t.speedHistory)
+}
+
+// SetHistoryMaxSamples sets the most speed samples kept in the speed history, rounded up to an
+// even number so samples can be averaged in pairs (0 = the default of 256)
+func (t *SpeedController) SetHistoryMaxSamples(samples int) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t.historyMax = samples + samples%2
+}
+
+// recordSpeedHistory adds a speed measurement to the speed history, averaging every historyStride
+// measurements into one sample (and doubling the stride whenever the history fills up)
+func (t *SpeedController) recordSpeedHistory(speed float64) {
+	stride := max(t.historyStride, 1)
+	t.historyCount++
+	t.historySum += speed
+
+	if t.historyCount < stride {
+		return
+	}
+
+	t.speedHistory = append(t.speedHistory, t.historySum/float64(t.historyCount))
+	t.historyCount, t.historySum = 0, 0.0
+
+	historyMax := t.historyMax
+
+	if historyMax <= 0 {
+		historyMax = defaultSpeedHistory
+	}
+
+	if len(t.speedHistory) < historyMax {
+		return
+	}
+
+	half := len(t.speedHistory) / 2
+
+	for i := range half {
+		t.speedHistory[i] = (t.speedHistory[2*i] + t.speedHistory[2*i+1]) / 2
+	}
+
+	t.speedHistory = t.speedHistory[:half]
+	t.historyStride = stride * 2
+}
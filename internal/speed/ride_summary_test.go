@@ -0,0 +1,216 @@
+package speed
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestGetRideSummary tests building a ride summary from a recorded session
+func TestGetRideSummary(t *testing.T) {
+	controller := NewSpeedController(td.window)
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	// Recorded session: 10 for 60s, 20 for 30s, stopped for 120s, then 15 for 30s
+	session := []struct {
+		offset time.Duration
+		speed  float64
+	}{
+		{0, 10.0},
+		{60 * time.Second, 20.0},
+		{90 * time.Second, 0.0},
+		{210 * time.Second, 15.0},
+		{240 * time.Second, 0.0},
+	}
+
+	for _, s := range session {
+		controller.updateSpeedAt(s.speed, start.Add(s.offset))
+	}
+
+	controller.UpdateWheelRevolutions(500, 2000)
+	summary := controller.GetRideSummary()
+
+	if summary.Distance != 1000.0 {
+		t.Errorf("Distance = %f, want 1000", summary.Distance)
+	}
+
+	if summary.MovingTime != 120*time.Second {
+		t.Errorf("MovingTime = %v, want 2m0s", summary.MovingTime)
+	}
+
+	if summary.ElapsedTime != 240*time.Second {
+		t.Errorf("ElapsedTime = %v, want 4m0s", summary.ElapsedTime)
+	}
+
+	// Time-weighted average over moving time: (10*60 + 20*30 + 15*30) / 120
+	if want := 13.75; math.Abs(summary.AverageSpeed-want) > 1e-9 {
+		t.Errorf("AverageSpeed = %f, want %f", summary.AverageSpeed, want)
+	}
+
+	if summary.MaxSpeed != 20.0 {
+		t.Errorf("MaxSpeed = %f, want 20", summary.MaxSpeed)
+	}
+
+}
+
+// TestMaxIntegrationStep tests that a gap longer than the maximum integration step doesn't accrue
+// the stale speed to the ride statistics
+func TestMaxIntegrationStep(t *testing.T) {
+	controller := NewSpeedController(td.window)
+	controller.SetMaxIntegrationStep(5 * time.Second)
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	// 10 m/s for 10s in 1s steps, then a 60s gap (e.g., a reconnect), then 10 m/s for another 5s
+	for i := 0; i <= 10; i++ {
+		controller.updateSpeedAt(10.0, start.Add(time.Duration(i)*time.Second))
+	}
+
+	for i := 70; i <= 75; i++ {
+		controller.updateSpeedAt(10.0, start.Add(time.Duration(i)*time.Second))
+	}
+
+	summary := controller.GetRideSummary()
+
+	// Without wheel revolutions, distance is integrated from speed, excluding the gap
+	if want := 150.0; math.Abs(summary.Distance-want) > 1e-9 {
+		t.Errorf("Distance = %f, want %f", summary.Distance, want)
+	}
+
+	if summary.MovingTime != 15*time.Second {
+		t.Errorf("MovingTime = %v, want 15s", summary.MovingTime)
+	}
+
+	// Revolution-derived distance is preferred when available
+	controller.UpdateWheelRevolutions(100, 2000)
+
+	if got := controller.GetDistance(); got != 200.0 {
+		t.Errorf("GetDistance() = %f, want 200", got)
+	}
+
+}
+
+// TestStatsWarmup tests that updates during the stats warmup are excluded from the ride statistics
+// but still drive the live speed
+func TestStatsWarmup(t *testing.T) {
+	fake := &fakeClock{wall: time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)}
+	controller := newSpeedController(1, fake)
+	controller.SetStatsWarmup(5 * time.Second)
+
+	// Garbage 30 m/s readings for the first 5s after connecting, then 10 m/s for 10s
+	for i := 0; i <= 15; i++ {
+		speed := 10.0
+		if i < 5 {
+			speed = 30.0
+		}
+
+		controller.UpdateSpeed(speed)
+		controller.UpdateWheelRevolutions(1, 2000)
+
+		if i < 5 && controller.MeasuredSpeed() != 30.0 {
+			t.Errorf("MeasuredSpeed() = %f during warmup, want 30", controller.MeasuredSpeed())
+		}
+
+		fake.advance(time.Second)
+	}
+
+	summary := controller.GetRideSummary()
+
+	if summary.MaxSpeed != 10.0 || summary.AverageSpeed != 10.0 {
+		t.Errorf("MaxSpeed = %f, AverageSpeed = %f, want 10 (warmup speeds excluded)", summary.MaxSpeed, summary.AverageSpeed)
+	}
+
+	if summary.MovingTime != 10*time.Second {
+		t.Errorf("MovingTime = %v, want 10s", summary.MovingTime)
+	}
+
+	// Wheel revolutions from t = 5s to 15s (2m each)
+	if summary.Distance != 22.0 {
+		t.Errorf("Distance = %f, want 22", summary.Distance)
+	}
+
+}
+
+// TestDistanceAccumulation tests that distance integrated over many small steps stays within a
+// tight tolerance of the exact distance
+func TestDistanceAccumulation(t *testing.T) {
+	controller := NewSpeedController(1)
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+	steps := 1000000
+
+	// 7.31 m/s for 1000s in 1ms steps
+	for i := 0; i <= steps; i++ {
+		controller.updateSpeedAt(7.31, start.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	if got, want := controller.GetDistance(), 7310.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("GetDistance() = %.12f, want %.12f", got, want)
+	}
+
+}
+
+// TestGetRideSummaryEmpty tests the ride summary before any speed measurements
+func TestGetRideSummaryEmpty(t *testing.T) {
+	summary := NewSpeedController(td.window).GetRideSummary()
+
+	if summary != (RideSummary{}) {
+		t.Errorf("GetRideSummary() = %+v, want zero summary", summary)
+	}
+
+}
+
+// TestSpeedHistory tests that the speed history stays bounded while spanning the whole ride
+func TestSpeedHistory(t *testing.T) {
+	controller := NewSpeedController(td.window)
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	// Ride at 10 for the first half, then at 20 for the second half
+	updates := defaultSpeedHistory * 3
+
+	for i := range updates {
+		speed := 10.0
+
+		if i >= updates/2 {
+			speed = 20.0
+		}
+
+		controller.updateSpeedAt(speed, start.Add(time.Duration(i)*time.Second))
+	}
+
+	history := controller.GetSpeedHistory()
+
+	if len(history) == 0 || len(history) > defaultSpeedHistory {
+		t.Fatalf("len(GetSpeedHistory()) = %d, want 1-%d", len(history), defaultSpeedHistory)
+	}
+
+	if history[0] != 10.0 || history[len(history)-1] != 20.0 {
+		t.Errorf("GetSpeedHistory() spans %f to %f, want 10 to 20", history[0], history[len(history)-1])
+	}
+
+	if got := NewSpeedController(td.window).GetSpeedHistory(); len(got) != 0 {
+		t.Errorf("GetSpeedHistory() = %v before any speed measurements, want empty", got)
+	}
+
+}
+
+// TestSpeedHistoryMaxSamples tests that the speed history stays within the configured bound over a
+// very long ride
+func TestSpeedHistoryMaxSamples(t *testing.T) {
+	controller := NewSpeedController(td.window)
+	controller.SetHistoryMaxSamples(99)
+	start := time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	// Ride for over a day at one speed measurement per second
+	for i := range 100000 {
+		controller.updateSpeedAt(float64(i%30), start.Add(time.Duration(i)*time.Second))
+
+		if samples := len(controller.speedHistory); samples > 100 {
+			t.Fatalf("speed history grew to %d samples after %d updates, want at most 100", samples, i+1)
+		}
+
+	}
+
+	if samples := len(controller.GetSpeedHistory()); samples < 50 {
+		t.Errorf("len(GetSpeedHistory()) = %d, want 50-100", samples)
+	}
+
+}
@@ -3,17 +3,46 @@ package speed
 import (
 	"container/ring"
 	"fmt"
+	"math"
+	"strconv"
 	"sync"
 	"time"
+
+	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
 )
 
-// SpeedController manages speed measurements with smoothing
+// SpeedController manages speed measurements (in meters per second) with smoothing. Before the
+// first speed measurement arrives (see HasData), all speeds, the distance and the ride summary are
+// zero, and the speed buffer holds only zeros
 type SpeedController struct {
 	speeds        *ring.Ring
 	window        int
 	currentSpeed  float64
 	smoothedSpeed float64
+	appliedSpeed  float64
+	displaySpeed  float64
 	lastUpdate    time.Time
+	distanceMM    uint64
+	rideStart     time.Time
+	movingTime    time.Duration
+	speedTimeSum  float64
+	speedTimeComp float64
+	maxSpeed      float64
+
+	latencyCompensation float64
+	gapHold             time.Duration
+	maxIntegrationStep  time.Duration
+	statsWarmup         time.Duration
+	displaySmoothing    time.Duration
+	maxChangeRate       float64
+	glitches            int
+	clock               eventClock
+
+	speedHistory  []float64
+	historyMax    int
+	historyStride int
+	historyCount  int
+	historySum    float64
 }
 
 // mutex manages concurrent access to SpeedController
@@ -21,6 +50,11 @@ var mutex sync.RWMutex
 
 // NewSpeedController creates a new speed controller with a specified window size
 func NewSpeedController(window int) *SpeedController {
+	return newSpeedController(window, newSystemClock())
+}
+
+// newSpeedController creates a new speed controller that stamps speed events using the given clock
+func newSpeedController(window int, source clock) *SpeedController {
 	r := ring.New(window)
 
 	// Initialize ring with zero values
@@ -32,17 +66,139 @@ func NewSpeedController(window int) *SpeedController {
 	return &SpeedController{
 		speeds: r,
 		window: window,
+		clock:  newEventClock(source),
 	}
 }
 
-// GetSmoothedSpeed returns the smoothed speed measurement
+// HasData reports whether any speed measurement has arrived yet
+func (t *SpeedController) HasData() bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return !t.lastUpdate.IsZero()
+}
+
+// GetSmoothedSpeed returns the smoothed (moving average) speed measurement
 func (t *SpeedController) GetSmoothedSpeed() float64 {
 	mutex.RLock()
 	defer mutex.RUnlock()
 
+	if t.gapExceeded(t.clock.now()) {
+		return 0.0
+	}
+
 	return t.smoothedSpeed
 }
 
+// MeasuredSpeed returns the most recent raw (unsmoothed) speed measurement, suitable for display
+func (t *SpeedController) MeasuredSpeed() float64 {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if t.gapExceeded(t.clock.now()) {
+		return 0.0
+	}
+
+	return t.currentSpeed
+}
+
+// AppliedSpeed returns the speed used to drive the video: the smoothed speed, with any latency
+// compensation applied
+func (t *SpeedController) AppliedSpeed() float64 {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if t.gapExceeded(t.clock.now()) {
+		return 0.0
+	}
+
+	return t.appliedSpeed
+}
+
+// DisplaySpeed returns the speed for on-screen display: the applied speed, further smoothed over
+// the display smoothing time so the displayed number stays calm
+func (t *SpeedController) DisplaySpeed() float64 {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if t.gapExceeded(t.clock.now()) {
+		return 0.0
+	}
+
+	return t.displaySpeed
+}
+
+// SetDisplaySmoothing sets the time constant of the display speed smoothing, which doesn't affect
+// the applied speed (0 = display the applied speed)
+func (t *SpeedController) SetDisplaySmoothing(smoothing time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t.displaySmoothing = smoothing
+}
+
+// smoothDisplaySpeed moves the display speed towards the applied speed, by an amount based on the
+// time since the last update relative to the display smoothing time (an exponential moving average)
+func (t *SpeedController) smoothDisplaySpeed(elapsed time.Duration) {
+
+	if t.displaySmoothing <= 0 {
+		t.displaySpeed = t.appliedSpeed
+		return
+	}
+
+	alpha := 1 - math.Exp(-max(elapsed, 0).Seconds()/t.displaySmoothing.Seconds())
+	t.displaySpeed += alpha * (t.appliedSpeed - t.displaySpeed)
+}
+
+// SetGapHold sets how long the last speed is held when speed measurements stop arriving, after
+// which the speed drops to zero (0 = hold the last speed indefinitely)
+func (t *SpeedController) SetGapHold(hold time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t.gapHold = hold
+}
+
+// SetMaxIntegrationStep sets the longest interval between speed measurements that is accrued to the
+// ride statistics, so a stale speed isn't integrated over a long gap (0 = no limit)
+func (t *SpeedController) SetMaxIntegrationStep(step time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t.maxIntegrationStep = step
+}
+
+// SetStatsWarmup sets how long after the first speed measurement updates are left out of the ride
+// statistics (e.g., while the sensor settles after connecting), without affecting the live speeds
+// (0 = no warmup)
+func (t *SpeedController) SetStatsWarmup(warmup time.Duration) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t.statsWarmup = warmup
+}
+
+// warmupEnd returns the time at which the stats warmup ends (zero if there's no warmup or no speed
+// measurement yet)
+func (t *SpeedController) warmupEnd() time.Time {
+
+	if t.statsWarmup <= 0 || t.rideStart.IsZero() {
+		return t.rideStart
+	}
+
+	return t.rideStart.Add(t.statsWarmup)
+}
+
+// warmingUp returns true if the ride statistics are still ignoring updates at the given time
+func (t *SpeedController) warmingUp(now time.Time) bool {
+	return t.statsWarmup > 0 && (t.rideStart.IsZero() || now.Before(t.warmupEnd()))
+}
+
+// gapExceeded reports whether speed measurements have stopped arriving for longer than the gap hold
+func (t *SpeedController) gapExceeded(now time.Time) bool {
+	return t.gapHold > 0 && !t.lastUpdate.IsZero() && now.Sub(t.lastUpdate) > t.gapHold
+}
+
 // GetSpeedBuffer returns the speed buffer as an array of formatted strings
 func (t *SpeedController) GetSpeedBuffer() []string {
 	mutex.RLock()
@@ -62,9 +218,24 @@ func (t *SpeedController) GetSpeedBuffer() []string {
 
 // UpdateSpeed updates the current speed measurement and calculates a smoothed average
 func (t *SpeedController) UpdateSpeed(speed float64) {
+
+	// Reject non-finite speeds, which would otherwise poison the smoothing buffer indefinitely
+	if math.IsNaN(speed) || math.IsInf(speed, 0) {
+		logger.Warn(logger.SPEED, "ignoring non-finite speed measurement: "+strconv.FormatFloat(speed, 'f', 2, 64))
+		return
+	}
+
+	t.updateSpeedAt(speed, t.clock.now())
+}
+
+// updateSpeedAt updates the speed measurement taken at the given time and the ride statistics
+func (t *SpeedController) updateSpeedAt(speed float64, now time.Time) {
 	mutex.Lock()
 	defer mutex.Unlock()
 
+	t.updateRideStats(speed, now)
+	t.checkChangeRate(speed, now)
+
 	t.currentSpeed = speed
 	t.speeds.Value = speed
 	t.speeds = t.speeds.Next()
@@ -80,5 +251,159 @@ func (t *SpeedController) UpdateSpeed(speed float64) {
 	})
 
 	t.smoothedSpeed = sum / float64(t.window)
-	t.lastUpdate = time.Now()
+	t.appliedSpeed = t.smoothedSpeed
+
+	if t.latencyCompensation > 0 {
+		t.appliedSpeed = t.compensateLatency(t.smoothedSpeed)
+	}
+
+	// Smooth the display speed (the first measurement is displayed as-is)
+	if t.lastUpdate.IsZero() {
+		t.displaySpeed = t.appliedSpeed
+	} else {
+		t.smoothDisplaySpeed(now.Sub(t.lastUpdate))
+	}
+
+	t.lastUpdate = now
+
+}
+
+// SetLatencyCompensation sets the fraction (0.0 to 1.0) of the smoothing lag to predict away
+// using the recent speed trend (0.0 = no compensation)
+func (t *SpeedController) SetLatencyCompensation(factor float64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t.latencyCompensation = factor
+}
+
+// compensateLatency extrapolates the moving average towards the current speed using the trend
+// across the window, capped to the buffered speed range to avoid overshoot
+func (t *SpeedController) compensateLatency(average float64) float64 {
+	newest := t.speeds.Prev().Value.(float64)
+	oldest := t.speeds.Value.(float64)
+
+	// A moving average lags a linear trend by half the window
+	predicted := average + (t.latencyCompensation * (newest - oldest) / 2)
+
+	low, high := math.Inf(1), math.Inf(-1)
+	t.speeds.Do(func(x interface{}) {
+
+		if x != nil {
+			low = math.Min(low, x.(float64))
+			high = math.Max(high, x.(float64))
+		}
+
+	})
+
+	return math.Min(math.Max(predicted, low), high)
+}
+
+// SetMaxChangeRate sets the speed change rate (in meters per second, per second) above which a speed
+// change is reported as a possible sensor glitch (0 = no reporting)
+func (t *SpeedController) SetMaxChangeRate(rate float64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	t.maxChangeRate = rate
+}
+
+// checkChangeRate reports (and counts) a speed change faster than the maximum change rate, which
+// usually indicates a sensor problem rather than a real change in speed
+func (t *SpeedController) checkChangeRate(speed float64, now time.Time) {
+
+	if t.maxChangeRate <= 0 || t.lastUpdate.IsZero() || !now.After(t.lastUpdate) {
+		return
+	}
+
+	interval := now.Sub(t.lastUpdate).Seconds()
+	change := math.Abs(speed - t.currentSpeed)
+
+	if change/interval > t.maxChangeRate {
+		t.glitches++
+		logger.Warn(logger.SPEED, fmt.Sprintf("speed changed by %.2f m/s in %.2fs, which is a possible sensor glitch", change, interval))
+	}
+
+}
+
+// updateRideStats accumulates the moving time and speed statistics up to the given time, treating
+// the previous speed as constant since the last update
+func (t *SpeedController) updateRideStats(speed float64, now time.Time) {
+
+	if t.rideStart.IsZero() {
+		t.rideStart = now
+	}
+
+	if t.warmingUp(now) {
+		return
+	}
+
+	t.recordSpeedHistory(speed)
+
+	// A gap longer than the gap hold was time spent stopped
+	if !t.lastUpdate.IsZero() && t.currentSpeed > 0 && !t.gapExceeded(now) {
+		interval := now.Sub(t.lastUpdate)
+
+		// Only accrue the part of the interval after the stats warmup
+		if end := t.warmupEnd(); t.lastUpdate.Before(end) {
+			interval = now.Sub(end)
+		}
+
+		// A gap longer than the maximum integration step (e.g., a reconnect) has no reliable speed
+		if t.maxIntegrationStep > 0 && interval > t.maxIntegrationStep {
+			logger.Debug(logger.SPEED, "not integrating stale speed over "+interval.String()+" gap")
+		} else {
+			t.movingTime += interval
+			t.addSpeedTime(t.currentSpeed * interval.Seconds())
+		}
+
+	}
+
+	t.maxSpeed = math.Max(t.maxSpeed, speed)
+}
+
+// addSpeedTime adds a distance (speed over an interval) to the integrated distance, using
+// compensated (Kahan) summation so many small steps don't accumulate floating-point drift
+func (t *SpeedController) addSpeedTime(meters float64) {
+	y := meters - t.speedTimeComp
+	sum := t.speedTimeSum + y
+	t.speedTimeComp = (sum - t.speedTimeSum) - y
+	t.speedTimeSum = sum
+}
+
+// UpdateWheelRevolutions adds the distance covered by wheel revolutions to the cumulative distance,
+// which (unlike integrating smoothed speed over time) is exact for revolution-based sensors
+func (t *SpeedController) UpdateWheelRevolutions(revs uint32, wheelCircumferenceMM int) {
+
+	if wheelCircumferenceMM <= 0 {
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if t.warmingUp(t.clock.now()) {
+		return
+	}
+
+	t.distanceMM += uint64(revs) * uint64(wheelCircumferenceMM)
+}
+
+// GetDistance returns the cumulative distance traveled in meters
+func (t *SpeedController) GetDistance() float64 {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return t.distance()
+}
+
+// distance returns the revolution-derived distance in meters when wheel revolutions are available,
+// falling back to the distance integrated from speed over time
+func (t *SpeedController) distance() float64 {
+
+	if t.distanceMM > 0 {
+		return float64(t.distanceMM) / 1000.0
+	}
+
+	return t.speedTimeSum
 }
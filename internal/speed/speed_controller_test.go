@@ -1,9 +1,12 @@
 package speed
 
 import (
+	"math"
 	"sync"
 	"testing"
 	"time"
+
+	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
 )
 
 // testData holds test constants and data
@@ -23,6 +26,11 @@ var td = testData{
 	sleepDuration: 10 * time.Millisecond,
 }
 
+// init initializes the logger for testing
+func init() {
+	logger.Initialize("debug")
+}
+
 // helper function to calculate average of speeds
 func calculateAverage(data []float64) float64 {
 
@@ -59,6 +67,54 @@ func TestNewSpeedController(t *testing.T) {
 
 }
 
+// TestNoDataState tests that every getter returns a zero (no data) value before the first speed
+// measurement arrives
+func TestNoDataState(t *testing.T) {
+	controller := NewSpeedController(td.window)
+	controller.SetGapHold(time.Second)
+	controller.SetDisplaySmoothing(time.Second)
+
+	if controller.HasData() {
+		t.Error("HasData() = true before any measurement, want false")
+	}
+
+	getters := map[string]func() float64{
+		"GetSmoothedSpeed": controller.GetSmoothedSpeed,
+		"MeasuredSpeed":    controller.MeasuredSpeed,
+		"AppliedSpeed":     controller.AppliedSpeed,
+		"DisplaySpeed":     controller.DisplaySpeed,
+		"GetDistance":      controller.GetDistance,
+	}
+
+	for name, getter := range getters {
+
+		if got := getter(); got != 0.0 {
+			t.Errorf("%s() = %f before any measurement, want 0", name, got)
+		}
+
+	}
+
+	for _, s := range controller.GetSpeedBuffer() {
+
+		if s != "0.00" {
+			t.Errorf("GetSpeedBuffer() = %v before any measurement, want only zeros", controller.GetSpeedBuffer())
+			break
+		}
+
+	}
+
+	if summary := controller.GetRideSummary(); summary != (RideSummary{}) {
+		t.Errorf("GetRideSummary() = %+v before any measurement, want zero summary", summary)
+	}
+
+	controller.UpdateSpeed(5.0)
+
+	if !controller.HasData() {
+		t.Error("HasData() = false after a measurement, want true")
+	}
+
+}
+
 // TestUpdateSpeed tests the UpdateSpeed method of SpeedController
 func TestUpdateSpeed(t *testing.T) {
 	controller := NewSpeedController(td.window)
@@ -155,3 +211,251 @@ func TestConcurrency(t *testing.T) {
 	}
 
 }
+
+// TestUpdateWheelRevolutions tests that revolution-derived distance matches the exact distance
+func TestUpdateWheelRevolutions(t *testing.T) {
+	controller := NewSpeedController(td.window)
+	circumferenceMM := 2105
+
+	// Accumulate revolutions across many small updates (including an empty update)
+	revs := []uint32{1, 2, 0, 3, 1, 1000, 7}
+	var totalRevs uint32
+
+	for _, r := range revs {
+		controller.UpdateWheelRevolutions(r, circumferenceMM)
+		totalRevs += r
+	}
+
+	// Exact distance is total revolutions * circumference
+	want := float64(totalRevs) * float64(circumferenceMM) / 1000.0
+
+	if got := controller.GetDistance(); got != want {
+		t.Errorf("GetDistance() = %f, want %f", got, want)
+	}
+
+	// Invalid circumference should not change distance
+	controller.UpdateWheelRevolutions(10, 0)
+
+	if got := controller.GetDistance(); got != want {
+		t.Errorf("GetDistance() = %f after invalid circumference, want %f", got, want)
+	}
+
+}
+
+// TestUpdateSpeedNonFinite tests that non-finite speeds are rejected, keeping the previous speed
+func TestUpdateSpeedNonFinite(t *testing.T) {
+	controller := NewSpeedController(td.window)
+
+	for _, speed := range td.speeds {
+		controller.UpdateSpeed(speed)
+	}
+
+	// Feed non-finite speeds
+	for _, speed := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		controller.UpdateSpeed(speed)
+	}
+
+	if got := controller.MeasuredSpeed(); got != td.speeds[len(td.speeds)-1] {
+		t.Errorf("MeasuredSpeed() = %f, want %f", got, td.speeds[len(td.speeds)-1])
+	}
+
+	if got := controller.GetSmoothedSpeed(); math.IsNaN(got) || math.IsInf(got, 0) || got != td.expectedSpeed {
+		t.Errorf("GetSmoothedSpeed() = %f, want %f", got, td.expectedSpeed)
+	}
+
+}
+
+// TestLatencyCompensation tests that latency compensation tracks a ramping speed more closely
+// than plain smoothing, without overshooting the recent speeds
+func TestLatencyCompensation(t *testing.T) {
+	plain := NewSpeedController(td.window)
+	compensated := NewSpeedController(td.window)
+	compensated.SetLatencyCompensation(1.0)
+
+	// Speed ramps up, then holds steady
+	ramp := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 10, 10, 10, 10}
+
+	for i, speed := range ramp {
+		plain.UpdateSpeed(speed)
+		compensated.UpdateSpeed(speed)
+
+		plainLag := speed - plain.GetSmoothedSpeed()
+		compensatedLag := speed - compensated.AppliedSpeed()
+
+		if math.Abs(compensatedLag) > math.Abs(plainLag)+1e-9 {
+			t.Errorf("sample %d: compensated lag %f exceeds uncompensated lag %f", i, compensatedLag, plainLag)
+		}
+
+		// Overshoot is bounded by the recent (buffered) speeds
+		if compensated.AppliedSpeed() > speed+1e-9 {
+			t.Errorf("sample %d: compensated speed %f overshoots speed %f", i, compensated.AppliedSpeed(), speed)
+		}
+
+	}
+
+	// Once the window is full of the ramp, a linear trend is fully compensated
+	ramping := NewSpeedController(td.window)
+	ramping.SetLatencyCompensation(1.0)
+
+	for _, speed := range ramp[:td.window+2] {
+		ramping.UpdateSpeed(speed)
+	}
+
+	if got, want := ramping.AppliedSpeed(), ramp[td.window+1]; math.Abs(got-want) > 1e-9 {
+		t.Errorf("compensated speed = %f, want %f", got, want)
+	}
+
+	// Steady speed is unaffected by compensation
+	if got := compensated.AppliedSpeed(); math.Abs(got-10.0) > 1e-9 {
+		t.Errorf("compensated steady speed = %f, want 10", got)
+	}
+
+}
+
+// TestGapHold tests that a short gap in speed measurements holds the last speed, while a long gap
+// drops the speed to zero
+func TestGapHold(t *testing.T) {
+	controller := NewSpeedController(1)
+	controller.SetGapHold(time.Hour)
+	controller.UpdateSpeed(10.0)
+
+	// Short gap holds the last speed
+	if got := controller.GetSmoothedSpeed(); got != 10.0 {
+		t.Errorf("GetSmoothedSpeed() = %f during short gap, want 10", got)
+	}
+
+	// Long gap drops the speed to zero
+	controller.SetGapHold(td.sleepDuration)
+	time.Sleep(2 * td.sleepDuration)
+
+	if got := controller.GetSmoothedSpeed(); got != 0.0 {
+		t.Errorf("GetSmoothedSpeed() = %f after long gap, want 0", got)
+	}
+
+	if got := controller.MeasuredSpeed(); got != 0.0 {
+		t.Errorf("MeasuredSpeed() = %f after long gap, want 0", got)
+	}
+
+	if got := controller.AppliedSpeed(); got != 0.0 {
+		t.Errorf("AppliedSpeed() = %f after long gap, want 0", got)
+	}
+
+	// A new measurement ends the gap
+	controller.UpdateSpeed(12.0)
+
+	if got := controller.GetSmoothedSpeed(); got != 12.0 {
+		t.Errorf("GetSmoothedSpeed() = %f after gap ends, want 12", got)
+	}
+
+}
+
+// TestNoSmoothing tests that a smoothing window of 1 passes each speed through exactly, with no latency
+func TestNoSmoothing(t *testing.T) {
+	controller := NewSpeedController(1)
+	controller.SetLatencyCompensation(1.0)
+
+	for _, speed := range []float64{3.2, 0.0, 17.25, 9.0, 9.0, 42.125} {
+		controller.UpdateSpeed(speed)
+
+		if got := controller.AppliedSpeed(); got != speed {
+			t.Errorf("AppliedSpeed() = %f, want %f", got, speed)
+		}
+
+	}
+
+}
+
+// TestMeasuredVsAppliedSpeed tests that the measured speed tracks the latest raw measurement, while
+// the applied speed is smoothed and kept within the range of the buffered measurements
+func TestMeasuredVsAppliedSpeed(t *testing.T) {
+	controller := NewSpeedController(td.window)
+
+	for _, speed := range td.speeds {
+		controller.UpdateSpeed(speed)
+	}
+
+	if got, want := controller.MeasuredSpeed(), td.speeds[len(td.speeds)-1]; got != want {
+		t.Errorf("MeasuredSpeed() = %f, want %f", got, want)
+	}
+
+	if got := controller.AppliedSpeed(); got != td.expectedSpeed {
+		t.Errorf("AppliedSpeed() = %f, want %f", got, td.expectedSpeed)
+	}
+
+	// A sudden spike is measured as-is, but compensation stays clamped to the buffered speeds
+	controller.SetLatencyCompensation(1.0)
+	controller.UpdateSpeed(50.0)
+
+	if got := controller.MeasuredSpeed(); got != 50.0 {
+		t.Errorf("MeasuredSpeed() = %f after spike, want 50", got)
+	}
+
+	if got := controller.AppliedSpeed(); got >= 50.0+1e-9 || got <= controller.GetSmoothedSpeed()-1e-9 {
+		t.Errorf("AppliedSpeed() = %f after spike, want within [%f, 50]", got, controller.GetSmoothedSpeed())
+	}
+
+}
+
+// TestDisplaySmoothing tests that the display speed lags the applied speed per the display
+// smoothing time, without affecting the applied speed
+func TestDisplaySmoothing(t *testing.T) {
+	controller := NewSpeedController(1)
+	controller.SetDisplaySmoothing(2 * time.Second)
+	start := time.Now()
+
+	controller.updateSpeedAt(0.0, start)
+	controller.updateSpeedAt(10.0, start.Add(2*time.Second))
+
+	// One time constant covers 1 - 1/e (about 63%) of the step, while control follows immediately
+	if got := controller.AppliedSpeed(); got != 10.0 {
+		t.Errorf("AppliedSpeed() = %f, want 10", got)
+	}
+
+	if got, want := controller.DisplaySpeed(), 10.0*(1-math.Exp(-1)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("DisplaySpeed() = %f, want %f", got, want)
+	}
+
+	// Display speed converges on a steady applied speed
+	for i := 2; i <= 20; i++ {
+		controller.updateSpeedAt(10.0, start.Add(time.Duration(2*i)*time.Second))
+	}
+
+	if got := controller.DisplaySpeed(); math.Abs(got-10.0) > 1e-3 {
+		t.Errorf("DisplaySpeed() = %f after steady speed, want 10", got)
+	}
+
+	// Without display smoothing, the display speed is the applied speed
+	controller.SetDisplaySmoothing(0)
+	controller.updateSpeedAt(4.0, start.Add(41*time.Second))
+
+	if got := controller.DisplaySpeed(); got != 4.0 {
+		t.Errorf("DisplaySpeed() = %f without display smoothing, want 4", got)
+	}
+
+}
+
+// TestMaxChangeRate tests that a fast speed swing is reported as a possible glitch, while gradual
+// speed changes are not
+func TestMaxChangeRate(t *testing.T) {
+	controller := NewSpeedController(td.window)
+	controller.SetMaxChangeRate(3.0) // m/s per second
+	start := time.Now()
+
+	// Gradual acceleration (1 m/s per second)
+	for i := 0; i <= 5; i++ {
+		controller.updateSpeedAt(float64(i), start.Add(time.Duration(i)*time.Second))
+	}
+
+	if got := controller.GetRideSummary().Glitches; got != 0 {
+		t.Errorf("Glitches = %d after gradual changes, want 0", got)
+	}
+
+	// Fast swing (20 m/s in half a second), then back again
+	controller.updateSpeedAt(25.0, start.Add(5500*time.Millisecond))
+	controller.updateSpeedAt(5.0, start.Add(6*time.Second))
+
+	if got := controller.GetRideSummary().Glitches; got != 2 {
+		t.Errorf("Glitches = %d after fast swings, want 2", got)
+	}
+
+}
@@ -0,0 +1,60 @@
+package speed
+
+import (
+	"math"
+	"strconv"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// Conversions from meters per second to the supported speed units
+const (
+	kmhPerMPS = 3.6
+	mphPerMPS = 2.23694
+
+	// Distance conversions
+	metersPerKilometer = 1000.0
+	metersPerMile      = 1609.344
+
+	// Default rounding step for reported distances (in kilometers or miles)
+	defaultDistancePrecision = 0.01
+)
+
+// ToSpeedUnits converts a speed in meters per second (as stored by SpeedController) to the speed units
+func ToSpeedUnits(mps float64, speedUnits string) float64 {
+	return mps * unitsPerMPS(speedUnits)
+}
+
+// FromSpeedUnits converts a speed in the speed units to meters per second
+func FromSpeedUnits(unitSpeed float64, speedUnits string) float64 {
+	return unitSpeed / unitsPerMPS(speedUnits)
+}
+
+// FormatDistance formats a distance in meters as kilometers or miles (matching the speed units),
+// rounded to the nearest multiple of the precision (0 = the default of 0.01)
+func FormatDistance(meters float64, speedUnits string, precision float64) string {
+	distance, unit := meters/metersPerKilometer, "km"
+
+	if speedUnits == config.SpeedUnitsMPH {
+		distance, unit = meters/metersPerMile, "mi"
+	}
+
+	if precision <= 0 {
+		precision = defaultDistancePrecision
+	}
+
+	// Show as many decimals as the precision needs (e.g., 0.1 = 1 decimal, 0.05 = 2 decimals)
+	decimals := max(0, int(math.Ceil(-math.Log10(precision)-1e-9)))
+
+	return strconv.FormatFloat(math.Round(distance/precision)*precision, 'f', decimals, 64) + " " + unit
+}
+
+// unitsPerMPS returns the number of speed units per meter per second
+func unitsPerMPS(speedUnits string) float64 {
+
+	if speedUnits == config.SpeedUnitsMPH {
+		return mphPerMPS
+	}
+
+	return kmhPerMPS
+}
@@ -0,0 +1,85 @@
+package speed
+
+import (
+	"math"
+	"testing"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// TestSpeedUnitConversions tests conversions between meters per second and the speed units
+func TestSpeedUnitConversions(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name       string
+		mps        float64
+		speedUnits string
+		want       float64
+	}{
+		{"km/h", 10.0, config.SpeedUnitsKMH, 36.0},
+		{"mph", 10.0, config.SpeedUnitsMPH, 22.3694},
+		{"stopped", 0.0, config.SpeedUnitsMPH, 0.0},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if got := ToSpeedUnits(tt.mps, tt.speedUnits); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ToSpeedUnits() = %f, want %f", got, tt.want)
+			}
+
+			if got := FromSpeedUnits(tt.want, tt.speedUnits); math.Abs(got-tt.mps) > 1e-9 {
+				t.Errorf("FromSpeedUnits() = %f, want %f", got, tt.mps)
+			}
+
+		})
+	}
+
+}
+
+// TestFormatDistance tests formatting distances in the speed units, rounded to the precision
+func TestFormatDistance(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name       string
+		meters     float64
+		speedUnits string
+		precision  float64
+		want       string
+	}{
+		{"default precision", 12345.0, config.SpeedUnitsKMH, 0.0, "12.35 km"},
+		{"one decimal", 12345.0, config.SpeedUnitsKMH, 0.1, "12.3 km"},
+		{"half units", 12345.0, config.SpeedUnitsKMH, 0.5, "12.5 km"},
+		{"three decimals", 12345.0, config.SpeedUnitsKMH, 0.001, "12.345 km"},
+		{"whole miles", 2500.0, config.SpeedUnitsMPH, 1.0, "2 mi"},
+		{"miles", 1609.344, config.SpeedUnitsMPH, 0.0, "1.00 mi"},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if got := FormatDistance(tt.meters, tt.speedUnits, tt.precision); got != tt.want {
+				t.Errorf("FormatDistance() = %q, want %q", got, tt.want)
+			}
+
+		})
+	}
+
+}
+
+// TestUpdateSpeedStoresSI tests that the speed controller stores speeds unchanged (in meters per second)
+func TestUpdateSpeedStoresSI(t *testing.T) {
+	controller := NewSpeedController(1)
+	controller.UpdateSpeed(FromSpeedUnits(36.0, config.SpeedUnitsKMH))
+
+	if got := controller.GetSmoothedSpeed(); math.Abs(got-10.0) > 1e-9 {
+		t.Errorf("GetSmoothedSpeed() = %f m/s, want 10 m/s", got)
+	}
+
+	if got := ToSpeedUnits(controller.GetSmoothedSpeed(), config.SpeedUnitsMPH); math.Abs(got-22.3694) > 1e-9 {
+		t.Errorf("smoothed speed = %f mph, want 22.3694 mph", got)
+	}
+
+}
@@ -0,0 +1,134 @@
+package tts
+
+import (
+	"context"
+	"math"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
+	speed "github.com/richbl/go-ble-sync-cycle/internal/speed"
+)
+
+// Number words used to build spoken announcements
+var (
+	onesWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	tensWords = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+)
+
+// Announcer periodically speaks the current cycle speed using a system TTS binary
+type Announcer struct {
+	config      config.TTSConfig
+	speedConfig config.SpeedConfig
+	speaking    atomic.Bool
+}
+
+// NewAnnouncer creates a new TTS speed announcer with the given configuration
+func NewAnnouncer(ttsConfig config.TTSConfig, speedConfig config.SpeedConfig) *Announcer {
+	return &Announcer{
+		config:      ttsConfig,
+		speedConfig: speedConfig,
+	}
+}
+
+// Start announces the smoothed speed at the configured interval until the context is cancelled
+func (a *Announcer) Start(ctx context.Context, speedController *speed.SpeedController) {
+	logger.Info(logger.TTS, "starting TTS speed announcements using "+a.config.Command+"...")
+
+	ticker := time.NewTicker(time.Duration(a.config.IntervalSecs) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info(logger.TTS, "context cancelled, stopping TTS speed announcements...")
+			return
+		case <-ticker.C:
+			appliedSpeed := speed.ToSpeedUnits(speedController.AppliedSpeed(), a.speedConfig.SpeedUnits)
+			a.announce(ctx, BuildAnnouncement(appliedSpeed, a.speedConfig.SpeedUnits))
+		}
+	}
+
+}
+
+// announce speaks the text in the background, skipping it if an announcement is already in progress
+func (a *Announcer) announce(ctx context.Context, text string) {
+
+	if !a.speaking.CompareAndSwap(false, true) {
+		logger.Debug(logger.TTS, "announcement still in progress, so skipping: "+text)
+		return
+	}
+
+	go func() {
+		defer a.speaking.Store(false)
+
+		logger.Debug(logger.TTS, "announcing: "+text)
+
+		if err := exec.CommandContext(ctx, a.config.Command, text).Run(); err != nil && ctx.Err() == nil {
+			logger.Warn(logger.TTS, "failed to run TTS command: "+err.Error())
+		}
+
+	}()
+}
+
+// BuildAnnouncement returns the spoken form of a speed (e.g., "twenty-two point five miles per hour")
+func BuildAnnouncement(speed float64, speedUnits string) string {
+	// Round to one decimal place to keep announcements short
+	tenths := int(math.Round(math.Abs(speed) * 10))
+	text := numberToWords(tenths / 10)
+
+	if tenths%10 != 0 {
+		text += " point " + onesWords[tenths%10]
+	}
+
+	return text + " " + unitsToWords(speedUnits)
+}
+
+// numberToWords returns the English words for a non-negative integer below one million
+func numberToWords(n int) string {
+
+	switch {
+	case n < 20:
+		return onesWords[n]
+	case n < 100:
+
+		if n%10 == 0 {
+			return tensWords[n/10]
+		}
+
+		return tensWords[n/10] + "-" + onesWords[n%10]
+	case n < 1000:
+
+		if n%100 == 0 {
+			return onesWords[n/100] + " hundred"
+		}
+
+		return onesWords[n/100] + " hundred " + numberToWords(n%100)
+	default:
+
+		if n%1000 == 0 {
+			return numberToWords(n/1000) + " thousand"
+		}
+
+		return numberToWords(n/1000) + " thousand " + numberToWords(n%1000)
+	}
+
+}
+
+// unitsToWords returns the spoken form of the configured speed units
+func unitsToWords(speedUnits string) string {
+
+	switch speedUnits {
+	case config.SpeedUnitsKMH:
+		return "kilometers per hour"
+	case config.SpeedUnitsMPH:
+		return "miles per hour"
+	default:
+		return strings.TrimSpace(speedUnits)
+	}
+
+}
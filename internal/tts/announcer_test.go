@@ -0,0 +1,37 @@
+package tts
+
+import (
+	"testing"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+)
+
+// TestBuildAnnouncement tests the spoken announcement text across units and values
+func TestBuildAnnouncement(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name       string
+		speed      float64
+		speedUnits string
+		want       string
+	}{
+		{"zero km/h", 0.0, config.SpeedUnitsKMH, "zero kilometers per hour"},
+		{"fractional km/h", 22.5, config.SpeedUnitsKMH, "twenty-two point five kilometers per hour"},
+		{"whole mph", 20.0, config.SpeedUnitsMPH, "twenty miles per hour"},
+		{"teen mph", 13.04, config.SpeedUnitsMPH, "thirteen miles per hour"},
+		{"rounded mph", 9.96, config.SpeedUnitsMPH, "ten miles per hour"},
+		{"hundreds km/h", 105.3, config.SpeedUnitsKMH, "one hundred five point three kilometers per hour"},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			if got := BuildAnnouncement(tt.speed, tt.speedUnits); got != tt.want {
+				t.Errorf("BuildAnnouncement() = %q, want %q", got, tt.want)
+			}
+
+		})
+	}
+
+}
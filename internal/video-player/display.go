@@ -0,0 +1,52 @@
+package video
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"slices"
+
+	config "github.com/richbl/go-ble-sync-cycle/internal/configuration"
+	logger "github.com/richbl/go-ble-sync-cycle/internal/logging"
+)
+
+// displayServerOS lists the operating systems where video output needs an X11 or Wayland display
+var displayServerOS = []string{"linux", "freebsd", "openbsd", "netbsd", "dragonfly"}
+
+// ErrNoDisplay is returned when there's no display to show the video on
+var ErrNoDisplay = errors.New("no display found (neither DISPLAY nor WAYLAND_DISPLAY is set), so the video can't be shown: " +
+	"set audio_only = true in the [video] section to play only the audio, or headless = \"audio_only\" to do so whenever there's no display")
+
+// CheckDisplay confirms that there's a display to show the video on, switching to audio-only
+// playback without one if configured (headless = "audio_only"), or returning ErrNoDisplay otherwise
+func CheckDisplay(videoConfig *config.VideoConfig) error {
+	return checkDisplay(videoConfig, displayAvailable(runtime.GOOS, os.Getenv))
+}
+
+// checkDisplay applies the configured headless behavior, given whether a display is available
+func checkDisplay(videoConfig *config.VideoConfig, hasDisplay bool) error {
+
+	if hasDisplay || videoConfig.AudioOnly {
+		return nil
+	}
+
+	if videoConfig.Headless != config.HeadlessAudioOnly {
+		return ErrNoDisplay
+	}
+
+	logger.Warn(logger.VIDEO, "no display found, so playing only the audio")
+	videoConfig.AudioOnly = true
+
+	return nil
+}
+
+// displayAvailable reports whether there's a display to show the video on, which on systems using
+// a display server means an X11 or Wayland display is set in the environment
+func displayAvailable(goos string, getenv func(string) string) bool {
+
+	if !slices.Contains(displayServerOS, goos) {
+		return true
+	}
+
+	return getenv("DISPLAY") != "" || getenv("WAYLAND_DISPLAY") != ""
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gen2brain/go-mpv"
@@ -16,12 +17,47 @@ import (
 	speed "github.com/richbl/go-ble-sync-cycle/internal/speed"
 )
 
+// Playback speed limits supported by the MPV media player
+const (
+	minMPVPlaybackSpeed = 0.01
+	maxMPVPlaybackSpeed = 100.0
+
+	// Playback speed used in preview mode (normal speed)
+	previewPlaybackSpeed = 1.0
+
+	// Frames dropped between playback updates above which the video is considered to be lagging,
+	// and the fraction of the lagging playback speed then used as a playback speed cap
+	maxFrameDropsPerUpdate = 5
+	lagCapFactor           = 0.9
+
+	// Consecutive playback updates without dropped frames after which a playback speed cap is
+	// lifted, so a one-off burst of dropped frames (e.g., a seek or loop restart) doesn't cap the
+	// rest of the ride
+	lagRecoveryUpdates = 30
+
+	// Fraction of the remaining difference to the target playback speed covered each playback
+	// update when interpolating between speed samples, and the difference at which it snaps to the
+	// target playback speed
+	interpolationFactor = 0.5
+	interpolationSnap   = 0.01
+
+	// Minimum number of moving speed samples needed to calibrate the reference speed
+	minCalibrationSamples = 10
+
+	// MPV user properties toggled by the pause hotkey, and set by the marker hotkey
+	manualPauseProperty = "user-data/ble-sync-cycle/manual-pause"
+	markerProperty      = "user-data/ble-sync-cycle/marker"
+)
+
 // Common errors for playback control
 var (
 	ErrOSDUpdate     = errors.New("failed to update OSD")
 	ErrPlaybackSpeed = errors.New("failed to set playback speed")
 	ErrVideoComplete = errors.New("playback completed: normal exit")
+	ErrCalibrated    = errors.New("calibration completed: normal exit")
 	ErrSpeedUpdate   = errors.New("failed to update video speed")
+	ErrMPVInit       = errors.New("failed to initialize mpv; confirm that mpv and libmpv are installed and operational")
+	ErrSpeedFilter   = errors.New("failed to update speed filter")
 )
 
 // wrapError wraps an error with a specific error type for more context
@@ -31,16 +67,55 @@ func wrapError(baseErr error, contextErr error) error {
 
 // PlaybackController manages video playback using MPV media player
 type PlaybackController struct {
-	config      config.VideoConfig
-	speedConfig config.SpeedConfig
-	player      *mpv.Mpv
+	config            config.VideoConfig
+	speedConfig       config.SpeedConfig
+	player            *mpv.Mpv
+	speedFilter       string
+	measuredSpeed     float64
+	displaySpeed      float64
+	lastPlaybackSpeed float64
+	appliedRate       float64
+	easedSpeed        float64
+	easeTarget        float64
+	lag               lagMonitor
+	frozen            frozenMonitor
+	rideStarted       bool
+	stoppedSince      time.Time
+	paused            bool
+	manualPause       bool
+	resumedAt         time.Time
+	cadenceController *speed.CadenceController
+}
+
+// rateMutex manages concurrent access to the applied playback rate
+var rateMutex sync.RWMutex
+
+// initializePlayer initializes a new MPV media player (replaceable in tests)
+var initializePlayer = func(player *mpv.Mpv) error {
+	return player.Initialize()
+}
+
+// lagMonitor tracks dropped video frames to cap playback speeds the video player can't sustain
+type lagMonitor struct {
+	lastDropCount int64
+	speedCap      float64
+	cleanUpdates  int
+}
+
+// frozenMonitor tracks the playback position to detect a video player that has stopped rendering
+// (frozen) while the video should be playing
+type frozenMonitor struct {
+	lastPosition float64
+	stuckSince   time.Time
+	frozen       bool
 }
 
 // NewPlaybackController creates a new video player with the given configuration
 func NewPlaybackController(videoConfig config.VideoConfig, speedConfig config.SpeedConfig) (*PlaybackController, error) {
 	player := mpv.New()
-	if err := player.Initialize(); err != nil {
-		return nil, err
+	if err := initializePlayer(player); err != nil {
+		player.TerminateDestroy()
+		return nil, wrapError(ErrMPVInit, err)
 	}
 
 	return &PlaybackController{
@@ -50,17 +125,34 @@ func NewPlaybackController(videoConfig config.VideoConfig, speedConfig config.Sp
 	}, nil
 }
 
+// SetCadenceController sets the cadence controller shown on the OSD (nil = no cadence display)
+func (p *PlaybackController) SetCadenceController(cadenceController *speed.CadenceController) {
+	p.cadenceController = cadenceController
+}
+
+// PlaybackRate returns the playback rate last applied to the video (after speed mapping, clamping,
+// lag capping, and resume ramping), or 0 while the video is paused
+func (p *PlaybackController) PlaybackRate() float64 {
+	rateMutex.RLock()
+	defer rateMutex.RUnlock()
+
+	return p.appliedRate
+}
+
+// setPlaybackRate records the playback rate applied to the video
+func (p *PlaybackController) setPlaybackRate(playbackSpeed float64) {
+	rateMutex.Lock()
+	defer rateMutex.Unlock()
+
+	p.appliedRate = playbackSpeed
+}
+
 // Start configures and starts the MPV media player
 func (p *PlaybackController) Start(ctx context.Context, speedController *speed.SpeedController) error {
 	logger.Info(logger.VIDEO, "starting MPV video player...")
 	defer p.player.TerminateDestroy()
 
-	if err := p.configureMPVPlayer(); err != nil {
-		return err
-	}
-
-	logger.Debug(logger.VIDEO, "loading video file: "+p.config.FilePath)
-	if err := p.loadMPVVideo(); err != nil {
+	if err := p.setupMPVPlayer(); err != nil {
 		return err
 	}
 
@@ -78,10 +170,9 @@ func (p *PlaybackController) Start(ctx context.Context, speedController *speed.S
 			logger.Info(logger.VIDEO, "context cancelled, stopping video player...")
 			return nil
 		case <-ticker.C:
-			reachedEOF, err := p.player.GetProperty("eof-reached", mpv.FormatFlag)
 
-			if err == nil && reachedEOF.(bool) {
-				return ErrVideoComplete
+			if p.reachedEOF() {
+				return p.endOfVideo(ctx)
 			}
 
 			if err := p.updatePlaybackSpeed(speedController, &lastSpeed); err != nil {
@@ -92,27 +183,344 @@ func (p *PlaybackController) Start(ctx context.Context, speedController *speed.S
 
 			}
 
+			if p.frozenCheckEnabled() {
+				p.checkFrozen(time.Now())
+			}
+
+		}
+	}
+
+}
+
+// Preview configures and starts the MPV media player at normal playback speed, ignoring sensor speed
+func (p *PlaybackController) Preview(ctx context.Context) error {
+	logger.Info(logger.VIDEO, "starting MPV video player in preview mode...")
+	defer p.player.TerminateDestroy()
+
+	if err := p.setupMPVPlayer(); err != nil {
+		return err
+	}
+
+	logger.Info(logger.VIDEO, logger.Cyan+"updating video playback speed to "+strconv.FormatFloat(previewPlaybackSpeed, 'f', 2, 64))
+
+	if err := p.updateMPVPlaybackSpeed(previewPlaybackSpeed); err != nil {
+		return wrapError(ErrPlaybackSpeed, err)
+	}
+
+	// Define preview loop interval
+	ticker := time.NewTicker(time.Millisecond * time.Duration(p.config.UpdateIntervalSec*1000))
+	defer ticker.Stop()
+
+	logger.Debug(logger.VIDEO, "entering MPV preview loop...")
+
+	// Start preview loop
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info(logger.VIDEO, "context cancelled, stopping video player...")
+			return nil
+		case <-ticker.C:
+
+			if p.reachedEOF() {
+				return p.endOfVideo(ctx)
+			}
+
+		}
+	}
+
+}
+
+// Calibrate plays the video at normal speed while the rider matches its pace for the calibration
+// window, then logs the speed_multiplier that plays the video at normal speed at the average speed
+// ridden, returning ErrCalibrated
+func (p *PlaybackController) Calibrate(ctx context.Context, speedController *speed.SpeedController, window time.Duration) error {
+	logger.Info(logger.VIDEO, "starting MPV video player in calibration mode: ride at the pace of the video for "+window.String()+"...")
+	defer p.player.TerminateDestroy()
+
+	if err := p.setupMPVPlayer(); err != nil {
+		return err
+	}
+
+	if err := p.updateMPVPlaybackSpeed(previewPlaybackSpeed); err != nil {
+		return wrapError(ErrPlaybackSpeed, err)
+	}
+
+	ticker := time.NewTicker(time.Millisecond * time.Duration(p.config.UpdateIntervalSec*1000))
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(window)
+	defer deadline.Stop()
+
+	var samples []float64
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info(logger.VIDEO, "context cancelled, stopping calibration...")
+			return nil
+		case <-deadline.C:
+			return p.finishCalibration(samples)
+		case <-ticker.C:
+
+			// End the calibration early if the video ends first
+			if p.reachedEOF() {
+				return p.finishCalibration(samples)
+			}
+
+			if speedController.HasData() {
+				samples = append(samples, speed.ToSpeedUnits(speedController.AppliedSpeed(), p.speedConfig.SpeedUnits))
+			}
+
+		}
+	}
+
+}
+
+// finishCalibration logs the calibration result for the speed samples, returning ErrCalibrated
+func (p *PlaybackController) finishCalibration(samples []float64) error {
+	referenceSpeed, multiplier, err := calibrateReference(samples)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(logger.VIDEO, "calibration complete: normal playback speed matched an average of "+
+		strconv.FormatFloat(referenceSpeed, 'f', 2, 64)+" "+p.speedConfig.SpeedUnits+", so set speed_multiplier = "+
+		strconv.FormatFloat(multiplier, 'f', 2, 64)+" in the [video] section")
+
+	if p.speedConfig.Invert {
+		logger.Info(logger.VIDEO, "with invert = true, also set invert_reference_speed = "+strconv.FormatFloat(referenceSpeed, 'f', 2, 64)+
+			" in the [speed] section")
+	}
+
+	return ErrCalibrated
+}
+
+// calibrateReference derives the reference speed (the average moving speed ridden at normal
+// playback speed) from the calibration speed samples, and the speed multiplier that maps the
+// reference speed to normal playback speed
+func calibrateReference(samples []float64) (float64, float64, error) {
+	var sum float64
+	var moving int
+
+	for _, sample := range samples {
+
+		if sample > 0 {
+			sum += sample
+			moving++
 		}
+
 	}
 
+	// Require riding for most of the calibration window
+	if moving < minCalibrationSamples || moving*2 < len(samples) {
+		return 0.0, 0.0, errors.New("not enough riding to calibrate (" + strconv.Itoa(moving) + " of " +
+			strconv.Itoa(len(samples)) + " speed samples while moving)")
+	}
+
+	referenceSpeed := sum / float64(moving)
+
+	// Invert the speed mapping (playback speed = cycle speed * speed_multiplier / 10) at 1.0x
+	return referenceSpeed, 10.0 * previewPlaybackSpeed / referenceSpeed, nil
+}
+
+// setupMPVPlayer configures the MPV video player and loads the video file
+func (p *PlaybackController) setupMPVPlayer() error {
+
+	if err := p.configureMPVPlayer(); err != nil {
+		return err
+	}
+
+	logger.Debug(logger.VIDEO, "loading video file: "+p.config.FilePath)
+	return p.loadMPVVideo()
+}
+
+// reachedEOF reports whether the MPV video player has reached the end of the video file
+func (p *PlaybackController) reachedEOF() bool {
+	reachedEOF, err := p.player.GetProperty("eof-reached", mpv.FormatFlag)
+
+	return err == nil && reachedEOF.(bool)
+}
+
+// mpvOption represents an MPV media player option name and value
+type mpvOption struct {
+	name  string
+	value string
 }
 
 // configureMPVPlayer configures the MPV video player settings
 func (p *PlaybackController) configureMPVPlayer() error {
-	// Keep video window open so we can later determine mpv video file EOF status
-	if err := p.player.SetOptionString("keep-open", "yes"); err != nil {
+
+	for _, option := range p.mpvOptions() {
+
+		if err := p.player.SetOptionString(option.name, option.value); err != nil {
+			return err
+		}
+
+	}
+
+	// Bind the (optional) pause and marker hotkeys
+	if err := p.bindPauseHotkey(); err != nil {
 		return err
 	}
 
+	return p.bindMarkerHotkey()
+}
+
+// mpvOptions returns the MPV video player options for the configured playback
+func (p *PlaybackController) mpvOptions() []mpvOption {
+	options := []mpvOption{
+		// Keep video window open so we can later determine mpv video file EOF status
+		{"keep-open", "yes"},
+		// Loop the video file, if configured to loop at the end of the video
+		{"loop-file", p.loopFile()},
+	}
+
+	// Play the audio only, with no video output (or window)
+	if p.config.AudioOnly {
+		logger.Debug(logger.VIDEO, "audio-only mode, so disabling video output")
+		return append(options, mpvOption{"vid", "no"}, mpvOption{"force-window", "no"})
+	}
+
 	// Set video window size, forcing window-maximized if scale factor is 1.0
 	if p.config.WindowScaleFactor == 1.0 {
 		logger.Debug(logger.VIDEO, "maximizing video window")
-		return p.player.SetOptionString("window-maximized", "yes")
+		return append(options, mpvOption{"window-maximized", "yes"})
 	}
 
 	// Scale video window
 	logger.Debug(logger.VIDEO, "scaling video window")
-	return p.player.SetOptionString("autofit", strconv.Itoa(int(p.config.WindowScaleFactor*100))+"%")
+	return append(options, mpvOption{"autofit", strconv.Itoa(int(p.config.WindowScaleFactor*100)) + "%"})
+}
+
+// bindPauseHotkey binds the configured pause hotkey in the MPV video player to toggle a user
+// property, which is then read on each playback update
+func (p *PlaybackController) bindPauseHotkey() error {
+
+	if !p.pauseHotkeyEnabled() {
+		return nil
+	}
+
+	logger.Debug(logger.VIDEO, "binding pause hotkey: "+p.config.PauseHotkey)
+
+	return p.bindHotkey(p.config.PauseHotkey, manualPauseProperty, "cycle-values "+manualPauseProperty+" yes no")
+}
+
+// bindMarkerHotkey binds the marker hotkey (if any) to set an MPV user property, polled on each
+// playback update
+func (p *PlaybackController) bindMarkerHotkey() error {
+
+	if !p.markerHotkeyEnabled() {
+		return nil
+	}
+
+	logger.Debug(logger.VIDEO, "binding marker hotkey: "+p.config.MarkerHotkey)
+
+	return p.bindHotkey(p.config.MarkerHotkey, markerProperty, "set "+markerProperty+" yes")
+}
+
+// bindHotkey binds a key in the video window to an MPV command on a user property, initializing the
+// property to "no"
+func (p *PlaybackController) bindHotkey(key, property, command string) error {
+
+	// Keyboard input in the video window is disabled by default when using libmpv
+	if err := p.player.SetOptionString("input-vo-keyboard", "yes"); err != nil {
+		return err
+	}
+
+	if err := p.player.SetPropertyString(property, "no"); err != nil {
+		return err
+	}
+
+	return p.player.Command([]string{"keybind", key, command})
+}
+
+// pauseHotkeyEnabled reports whether a pause hotkey is configured (there's no video window to
+// receive it in audio-only mode)
+func (p *PlaybackController) pauseHotkeyEnabled() bool {
+	return p.config.PauseHotkey != "" && !p.config.AudioOnly
+}
+
+// markerHotkeyEnabled returns true if a marker hotkey is configured (and there's a video window to
+// receive it)
+func (p *PlaybackController) markerHotkeyEnabled() bool {
+	return p.config.MarkerHotkey != "" && !p.config.AudioOnly
+}
+
+// checkMarker logs a ride marker if the marker hotkey was pressed since the last playback update
+func (p *PlaybackController) checkMarker(speedController *speed.SpeedController) {
+
+	if p.player.GetPropertyString(markerProperty) != "yes" {
+		return
+	}
+
+	if err := p.player.SetPropertyString(markerProperty, "no"); err != nil {
+		logger.Warn(logger.VIDEO, "failed to reset ride marker: "+err.Error())
+	}
+
+	logger.Info(logger.VIDEO, p.formatMarker(time.Now(), speedController.GetDistance()))
+}
+
+// formatMarker formats a ride marker log message with the time and cumulative distance
+func (p *PlaybackController) formatMarker(now time.Time, meters float64) string {
+	return "ride marker at " + now.Format(time.TimeOnly) + ", distance " + p.formatDistance(meters)
+}
+
+// hotkeyPaused reports whether the pause hotkey has toggled the video into a manual pause
+func (p *PlaybackController) hotkeyPaused() bool {
+	return p.player.GetPropertyString(manualPauseProperty) == "yes"
+}
+
+// setManualPause sets the manual pause state, which overrides the speed-driven playback state
+// until released
+func (p *PlaybackController) setManualPause(pause bool, lastSpeed *float64) {
+
+	if pause == p.manualPause {
+		return
+	}
+
+	p.manualPause = pause
+
+	if pause {
+		logger.Info(logger.VIDEO, "video paused by hotkey, so ignoring sensor speed until resumed")
+		return
+	}
+
+	logger.Info(logger.VIDEO, "video resumed by hotkey")
+
+	// Force a playback update at the current speed
+	*lastSpeed = 0.0
+}
+
+// loopFile returns the MPV loop-file option value for the configured end of video behavior
+func (p *PlaybackController) loopFile() string {
+
+	if p.config.OnEnd == config.OnEndLoop {
+		return "inf"
+	}
+
+	return "no"
+}
+
+// endOfVideo handles the end of the video per the configured end of video behavior, either
+// returning ErrVideoComplete to shut down the application, or holding on the last frame until
+// the context is cancelled
+func (p *PlaybackController) endOfVideo(ctx context.Context) error {
+
+	if p.config.OnEnd != config.OnEndHold {
+		logger.Info(logger.VIDEO, "end of video reached, so stopping...")
+		return ErrVideoComplete
+	}
+
+	logger.Info(logger.VIDEO, "end of video reached, so holding on the last frame...")
+
+	if err := p.setMPVPauseState(true); err != nil {
+		logger.Warn(logger.VIDEO, "failed to pause video on the last frame: "+err.Error())
+	}
+
+	<-ctx.Done()
+	logger.Info(logger.VIDEO, "context cancelled, stopping video player...")
+
+	return nil
 }
 
 // loadMPVVideo loads the video file into the MPV video player
@@ -122,42 +530,173 @@ func (p *PlaybackController) loadMPVVideo() error {
 
 // updatePlaybackSpeed updates the video playback speed based on the sensor speed
 func (p *PlaybackController) updatePlaybackSpeed(speedController *speed.SpeedController, lastSpeed *float64) error {
-	currentSpeed := speedController.GetSmoothedSpeed()
+	// Convert from meters per second, as stored by the speed controller, to the speed units
+	currentSpeed := p.applyZeroDeadband(speed.ToSpeedUnits(speedController.AppliedSpeed(), p.speedConfig.SpeedUnits))
+	p.measuredSpeed = speed.ToSpeedUnits(speedController.MeasuredSpeed(), p.speedConfig.SpeedUnits)
+	p.displaySpeed = p.applyZeroDeadband(speed.ToSpeedUnits(speedController.DisplaySpeed(), p.speedConfig.SpeedUnits))
 	p.logSpeedInfo(speedController, currentSpeed)
 
+	// Keep the video paused until the first speed measurement arrives
+	if !speedController.HasData() {
+		return p.pausePlayback()
+	}
+
+	if p.pauseHotkeyEnabled() {
+		p.setManualPause(p.hotkeyPaused(), lastSpeed)
+	}
+
+	if p.markerHotkeyEnabled() {
+		p.checkMarker(speedController)
+	}
+
 	return p.checkSpeedState(currentSpeed, lastSpeed)
 }
 
 // logSpeedInfo logs the sensor speed details
 func (p *PlaybackController) logSpeedInfo(sc *speed.SpeedController, currentSpeed float64) {
-	logger.Debug(logger.VIDEO, "sensor speed buffer: ["+strings.Join(sc.GetSpeedBuffer(), " ")+"]")
+	logger.Debug(logger.VIDEO, "sensor speed buffer (m/s): ["+strings.Join(sc.GetSpeedBuffer(), " ")+"]")
 	logger.Info(logger.VIDEO, logger.Magenta+"smoothed sensor speed: "+strconv.FormatFloat(currentSpeed, 'f', 2, 64)+" "+p.speedConfig.SpeedUnits)
+	logger.Debug(logger.VIDEO, "cumulative distance: "+p.formatDistance(sc.GetDistance()))
+}
+
+// formatDistance formats a distance in meters as kilometers or miles, matching the configured speed units
+func (p *PlaybackController) formatDistance(meters float64) string {
+	return speed.FormatDistance(meters, p.speedConfig.SpeedUnits, p.speedConfig.DistancePrecision)
 }
 
 // checkSpeedState checks the current sensor speed and adjusts video playback
 func (p *PlaybackController) checkSpeedState(currentSpeed float64, lastSpeed *float64) error {
 
-	if currentSpeed == 0 {
+	// A manual pause takes precedence over the sensor speed
+	if p.manualPause {
+		return p.haltPlayback()
+	}
+
+	if p.isStopped(currentSpeed) {
+
+		if !p.shouldPause(time.Now()) {
+			return nil
+		}
+
 		return p.pausePlayback()
 	}
 
+	p.markMoving()
+
+	// Ease the playback speed up when resuming from a pause, and always update playback then, since
+	// the speed may be within the threshold of the speed before the pause
+	now := time.Now()
+	resuming := p.paused
+
+	if resuming {
+		p.startResumeRamp(now)
+	}
+
 	deltaSpeed := math.Abs(currentSpeed - *lastSpeed)
 
 	logger.Debug(logger.VIDEO, logger.Magenta+"last playback speed: "+strconv.FormatFloat(*lastSpeed, 'f', 2, 64)+" "+p.speedConfig.SpeedUnits)
 	logger.Debug(logger.VIDEO, logger.Magenta+"sensor speed delta: "+strconv.FormatFloat(deltaSpeed, 'f', 2, 64)+" "+p.speedConfig.SpeedUnits)
 	logger.Debug(logger.VIDEO, logger.Magenta+"playback speed update threshold: "+strconv.FormatFloat(p.speedConfig.SpeedThreshold, 'f', 2, 64)+" "+p.speedConfig.SpeedUnits)
 
-	if deltaSpeed > p.speedConfig.SpeedThreshold {
+	if resuming || deltaSpeed > p.speedConfig.SpeedThreshold || p.resumeRamping(now) || p.interpolating() {
 		return p.adjustPlayback(currentSpeed, lastSpeed)
 	}
 
 	return nil
 }
 
+// applyZeroDeadband snaps a speed below the zero deadband to zero, so a smoothed speed trailing off
+// just above zero stops the video cleanly
+func (p *PlaybackController) applyZeroDeadband(cycleSpeed float64) float64 {
+
+	if cycleSpeed < p.speedConfig.ZeroDeadband {
+		return 0.0
+	}
+
+	return cycleSpeed
+}
+
+// isStopped reports whether the speed counts as stopped, using a hysteresis band between the pause
+// enter and exit speeds so that a speed hovering at the boundary doesn't flap between pause and play
+func (p *PlaybackController) isStopped(currentSpeed float64) bool {
+
+	if p.paused {
+		return currentSpeed <= p.speedConfig.PauseExitSpeed
+	}
+
+	return currentSpeed <= p.speedConfig.PauseEnterSpeed
+}
+
+// startResumeRamp records a resume from a pause, starting the resume ramp (if configured)
+func (p *PlaybackController) startResumeRamp(now time.Time) {
+	p.paused = false
+
+	if p.config.ResumeRampSecs > 0 {
+		p.resumedAt = now
+	}
+
+}
+
+// resumeRamping reports whether the playback speed is still ramping up after a resume
+func (p *PlaybackController) resumeRamping(now time.Time) bool {
+	return !p.resumedAt.IsZero() && now.Sub(p.resumedAt) < p.resumeRampDuration()
+}
+
+// resumeRampDuration returns the configured resume ramp duration
+func (p *PlaybackController) resumeRampDuration() time.Duration {
+	return time.Duration(p.config.ResumeRampSecs * float64(time.Second))
+}
+
+// applyResumeRamp scales a playback speed by the progress through the resume ramp, easing the
+// playback speed from zero up to the target speed
+func (p *PlaybackController) applyResumeRamp(playbackSpeed float64, now time.Time) float64 {
+
+	if !p.resumeRamping(now) {
+		p.resumedAt = time.Time{}
+		return playbackSpeed
+	}
+
+	progress := float64(now.Sub(p.resumedAt)) / float64(p.resumeRampDuration())
+
+	return math.Max(playbackSpeed*progress, minMPVPlaybackSpeed)
+}
+
+// shouldPause reports whether the speed has been zero for longer than the pause grace period, which
+// is longer before the ride starts (e.g., while clipping in) than once the ride has started
+func (p *PlaybackController) shouldPause(now time.Time) bool {
+
+	if p.stoppedSince.IsZero() {
+		p.stoppedSince = now
+	}
+
+	graceSecs := p.speedConfig.RidingPauseGraceSecs
+
+	if !p.rideStarted {
+		graceSecs = p.speedConfig.StartupPauseGraceSecs
+	}
+
+	return now.Sub(p.stoppedSince) >= time.Duration(graceSecs*float64(time.Second))
+}
+
+// markMoving records that speed was detected, starting the ride and ending any pause grace period
+func (p *PlaybackController) markMoving() {
+	p.rideStarted = true
+	p.stoppedSince = time.Time{}
+}
+
 // pausePlayback pauses the video playback in the MPV media player
 func (p *PlaybackController) pausePlayback() error {
 	logger.Debug(logger.VIDEO, "no speed detected, so pausing video")
 
+	return p.haltPlayback()
+}
+
+// haltPlayback pauses the video, resetting the playback rate, eased speed and OSD to zero
+func (p *PlaybackController) haltPlayback() error {
+	p.paused = true
+	p.easedSpeed = 0.0
+	p.setPlaybackRate(0.0)
+
 	if err := p.updateMPVDisplay(0.0, 0.0); err != nil {
 		return wrapError(ErrOSDUpdate, err)
 	}
@@ -167,15 +706,48 @@ func (p *PlaybackController) pausePlayback() error {
 
 // adjustPlayback adjusts the video playback speed
 func (p *PlaybackController) adjustPlayback(currentSpeed float64, lastSpeed *float64) error {
-	playbackSpeed := (currentSpeed * p.config.SpeedMultiplier) / 10.0
+	stages := playbackStages{mapped: p.mapSpeedToRate(currentSpeed), clamped: p.mapSpeedToPlayback(currentSpeed)}
+
+	// Cap the playback speed if the video player is dropping frames at the last playback speed
+	if dropCount, err := p.player.GetProperty("frame-drop-count", mpv.FormatInt64); err == nil {
+
+		capped, lifted := p.lag.update(dropCount.(int64), p.lastPlaybackSpeed)
+
+		if capped {
+			logger.Warn(logger.VIDEO, "video player is dropping frames at "+strconv.FormatFloat(p.lastPlaybackSpeed, 'f', 2, 64)+
+				"x, so capping playback speed at "+strconv.FormatFloat(p.lag.speedCap, 'f', 2, 64)+"x")
+		}
+
+		if lifted {
+			logger.Info(logger.VIDEO, "video player no longer dropping frames, so lifting playback speed cap")
+		}
+
+	}
+
+	stages.lagCapped = p.lag.apply(stages.clamped)
+	stages.eased = p.interpolatePlaybackSpeed(stages.lagCapped)
+	stages.ramped = p.applyResumeRamp(stages.eased, time.Now())
+	playbackSpeed := stages.ramped
+
+	// Trace how the playback speed was derived whenever it changes
+	if playbackSpeed != p.lastPlaybackSpeed {
+		logger.Debug(logger.VIDEO, p.formatDecisionTrace(p.measuredSpeed, currentSpeed, stages))
+		p.lastPlaybackSpeed = playbackSpeed
+	}
+
 	logger.Info(logger.VIDEO, logger.Cyan+"updating video playback speed to "+strconv.FormatFloat(playbackSpeed, 'f', 2, 64))
 
 	if err := p.updateMPVPlaybackSpeed(playbackSpeed); err != nil {
 		return wrapError(ErrPlaybackSpeed, err)
 	}
 
+	p.setPlaybackRate(playbackSpeed)
 	*lastSpeed = currentSpeed
 
+	if err := p.updateMPVSpeedFilter(currentSpeed, playbackSpeed); err != nil {
+		return wrapError(ErrSpeedFilter, err)
+	}
+
 	if err := p.updateMPVDisplay(currentSpeed, playbackSpeed); err != nil {
 		return wrapError(ErrOSDUpdate, err)
 	}
@@ -183,6 +755,203 @@ func (p *PlaybackController) adjustPlayback(currentSpeed float64, lastSpeed *flo
 	return p.setMPVPauseState(false)
 }
 
+// interpolatePlaybackSpeed eases the playback speed part of the way toward the target playback speed
+// (if interpolating between speed samples), so a sparse speed update doesn't visibly step the video
+// rate: the playback speed only ever moves toward the last measured speed, never past it
+func (p *PlaybackController) interpolatePlaybackSpeed(target float64) float64 {
+	p.easeTarget = target
+
+	// Start from the target playback speed, rather than easing up from a pause
+	if !p.speedConfig.InterpolateBetweenSamples || p.easedSpeed == 0.0 {
+		p.easedSpeed = target
+		return target
+	}
+
+	p.easedSpeed += (target - p.easedSpeed) * interpolationFactor
+
+	if math.Abs(target-p.easedSpeed) < interpolationSnap {
+		p.easedSpeed = target
+	}
+
+	return p.easedSpeed
+}
+
+// interpolating reports whether the playback speed is still easing toward the target playback speed
+func (p *PlaybackController) interpolating() bool {
+	return p.speedConfig.InterpolateBetweenSamples && p.easedSpeed != p.easeTarget
+}
+
+// mapSpeedToPlayback translates a (non-zero) cycle speed into a bounded video playback speed
+func (p *PlaybackController) mapSpeedToPlayback(cycleSpeed float64) float64 {
+	return p.clampPlaybackSpeed(p.mapSpeedToRate(cycleSpeed))
+}
+
+// mapSpeedToRate translates a cycle speed into an (unbounded) video playback speed
+func (p *PlaybackController) mapSpeedToRate(cycleSpeed float64) float64 {
+
+	// Mirror the cycle speed around the reference speed so that riding faster slows the video
+	if p.speedConfig.Invert {
+		cycleSpeed = (2 * p.speedConfig.InvertReferenceSpeed) - cycleSpeed
+	}
+
+	return (cycleSpeed * p.config.SpeedMultiplier) / 10.0
+}
+
+// clampPlaybackSpeed bounds a playback speed to the configured limits (if any), then to what MPV supports
+func (p *PlaybackController) clampPlaybackSpeed(playbackSpeed float64) float64 {
+
+	if p.config.MinPlaybackSpeed > 0 {
+		playbackSpeed = math.Max(playbackSpeed, p.config.MinPlaybackSpeed)
+	}
+
+	if p.config.MaxPlaybackSpeed > 0 {
+		playbackSpeed = math.Min(playbackSpeed, p.config.MaxPlaybackSpeed)
+	}
+
+	return math.Min(math.Max(playbackSpeed, minMPVPlaybackSpeed), maxMPVPlaybackSpeed)
+}
+
+// update records the video player dropped-frame count, lowering the playback speed cap if frames
+// were dropped at the given playback speed (or lifting it after lagRecoveryUpdates updates without
+// dropped frames), and reports whether the cap was first applied, and whether it was lifted
+func (l *lagMonitor) update(dropCount int64, playbackSpeed float64) (bool, bool) {
+	drops := dropCount - l.lastDropCount
+	l.lastDropCount = dropCount
+
+	// Lift the cap once the video player has gone long enough without dropping frames
+	if drops > 0 {
+		l.cleanUpdates = 0
+	} else if l.speedCap > 0 {
+		l.cleanUpdates++
+
+		if l.cleanUpdates >= lagRecoveryUpdates {
+			l.speedCap = 0
+			l.cleanUpdates = 0
+
+			return false, true
+		}
+
+	}
+
+	// Only cap faster than normal playback speeds, which are what the video player struggles with
+	if drops <= maxFrameDropsPerUpdate || playbackSpeed <= previewPlaybackSpeed {
+		return false, false
+	}
+
+	firstCap := l.speedCap == 0
+	speedCap := math.Max(playbackSpeed*lagCapFactor, previewPlaybackSpeed)
+
+	if firstCap || speedCap < l.speedCap {
+		l.speedCap = speedCap
+	}
+
+	return firstCap, false
+}
+
+// apply returns the playback speed, limited to the playback speed cap (if any)
+func (l *lagMonitor) apply(playbackSpeed float64) float64 {
+
+	if l.speedCap > 0 {
+		return math.Min(playbackSpeed, l.speedCap)
+	}
+
+	return playbackSpeed
+}
+
+// frozenCheckEnabled reports whether a frozen video player should be detected
+func (p *PlaybackController) frozenCheckEnabled() bool {
+	return p.config.FrozenTimeoutSecs > 0
+}
+
+// checkFrozen reports a video player whose playback position has stopped advancing while the video
+// is playing (and when its playback position advances again)
+func (p *PlaybackController) checkFrozen(now time.Time) {
+	position, err := p.player.GetProperty("time-pos", mpv.FormatDouble)
+	if err != nil {
+		return
+	}
+
+	playbackRate := p.PlaybackRate()
+
+	if p.paused {
+		playbackRate = 0.0
+	}
+
+	timeout := time.Duration(p.config.FrozenTimeoutSecs * float64(time.Second))
+	wasFrozen := p.frozen.frozen
+
+	if p.frozen.update(position.(float64), playbackRate, now, timeout) {
+		logger.Warn(logger.VIDEO, fmt.Sprintf("video player appears frozen: playback position stuck at %.2fs for %s while playing at %.2fx",
+			p.frozen.lastPosition, now.Sub(p.frozen.stuckSince).Round(time.Second), playbackRate))
+	} else if wasFrozen && !p.frozen.frozen {
+		logger.Info(logger.VIDEO, "video player playback position is advancing again")
+	}
+
+}
+
+// update records the playback position while the video plays at the given playback rate, and
+// returns true when the position is first found stuck for at least the timeout
+func (f *frozenMonitor) update(position, playbackRate float64, now time.Time, timeout time.Duration) bool {
+
+	// Only a playing video is expected to advance, so a moving or paused video isn't frozen
+	if position != f.lastPosition || playbackRate <= 0 {
+		f.lastPosition = position
+		f.stuckSince = time.Time{}
+		f.frozen = false
+
+		return false
+	}
+
+	if f.stuckSince.IsZero() {
+		f.stuckSince = now
+	}
+
+	if f.frozen || now.Sub(f.stuckSince) < timeout {
+		return false
+	}
+
+	f.frozen = true
+
+	return true
+}
+
+// playbackStages holds the playback speed after each stage of its derivation from the cycle speed
+type playbackStages struct {
+	mapped    float64
+	clamped   float64
+	lagCapped float64
+	eased     float64
+	ramped    float64
+}
+
+// formatDecisionTrace describes how a playback speed was derived from the applied sensor speed,
+// listing only the stages after mapping that changed it
+func (p *PlaybackController) formatDecisionTrace(measuredSpeed, appliedSpeed float64, stages playbackStages) string {
+	trace := fmt.Sprintf("playback decision: measured %.2f %s, applied %.2f %s, mapped %.2fx",
+		measuredSpeed, p.speedConfig.SpeedUnits, appliedSpeed, p.speedConfig.SpeedUnits, stages.mapped)
+
+	previous := stages.mapped
+
+	for _, stage := range []struct {
+		name  string
+		speed float64
+	}{
+		{"clamped", stages.clamped},
+		{"lag capped", stages.lagCapped},
+		{"eased", stages.eased},
+		{"ramped", stages.ramped},
+	} {
+
+		if stage.speed != previous {
+			trace += fmt.Sprintf(", %s %.2fx", stage.name, stage.speed)
+		}
+
+		previous = stage.speed
+	}
+
+	return trace + fmt.Sprintf(", playback %.2fx", stages.ramped)
+}
+
 // updateMPVDisplay updates the MPV media player on-screen display
 func (p *PlaybackController) updateMPVDisplay(cycleSpeed, playbackSpeed float64) error {
 
@@ -196,13 +965,18 @@ func (p *PlaybackController) updateMPVDisplay(cycleSpeed, playbackSpeed float64)
 	if cycleSpeed > 0 {
 
 		if p.config.OnScreenDisplay.DisplayCycleSpeed {
-			osdText += fmt.Sprintf(" Cycle Speed: %.2f %s\n", cycleSpeed, p.speedConfig.SpeedUnits)
+			osdText += fmt.Sprintf(" Cycle Speed: %.2f %s (measured %.2f %s)\n", p.displaySpeed, p.speedConfig.SpeedUnits,
+				p.measuredSpeed, p.speedConfig.SpeedUnits)
 		}
 
 		if p.config.OnScreenDisplay.DisplayPlaybackSpeed {
 			osdText += fmt.Sprintf(" Playback Speed: %.2fx\n", playbackSpeed)
 		}
 
+		if p.config.OnScreenDisplay.DisplayCadence && p.cadenceController != nil {
+			osdText += fmt.Sprintf(" Cadence: %.0f rpm\n", p.cadenceController.AverageCadence())
+		}
+
 	} else {
 		osdText = " Paused"
 	}
@@ -210,6 +984,38 @@ func (p *PlaybackController) updateMPVDisplay(cycleSpeed, playbackSpeed float64)
 	return p.player.SetOptionString("osd-msg1", osdText)
 }
 
+// updateMPVSpeedFilter applies the speed filter rendered for the given speeds, if configured and changed
+func (p *PlaybackController) updateMPVSpeedFilter(cycleSpeed, playbackSpeed float64) error {
+
+	if p.config.SpeedFilter == "" || p.config.AudioOnly {
+		return nil
+	}
+
+	filter := renderSpeedFilter(p.config.SpeedFilter, cycleSpeed, playbackSpeed)
+
+	if filter == p.speedFilter {
+		return nil
+	}
+
+	logger.Debug(logger.VIDEO, "updating video speed filter to "+filter)
+
+	if err := p.player.Command([]string{"vf", "set", filter}); err != nil {
+		return err
+	}
+
+	p.speedFilter = filter
+
+	return nil
+}
+
+// renderSpeedFilter replaces the {speed} and {rate} placeholders in the speed filter template
+func renderSpeedFilter(template string, cycleSpeed, playbackSpeed float64) string {
+	return strings.NewReplacer(
+		config.SpeedFilterSpeed, strconv.FormatFloat(cycleSpeed, 'f', 2, 64),
+		config.SpeedFilterRate, strconv.FormatFloat(playbackSpeed, 'f', 2, 64),
+	).Replace(template)
+}
+
 // updateMPVPlaybackSpeed sets the video playback speed
 func (p *PlaybackController) updateMPVPlaybackSpeed(playbackSpeed float64) error {
 	return p.player.SetProperty("speed", mpv.FormatDouble, playbackSpeed)
@@ -2,6 +2,7 @@ package video
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -65,6 +66,21 @@ func TestNewPlaybackController(t *testing.T) {
 	assert.NoError(t, err, "should create controller without error")
 }
 
+// TestNewPlaybackControllerInitError tests that an MPV initialization failure is reported as the
+// friendly ErrMPVInit error
+func TestNewPlaybackControllerInitError(t *testing.T) {
+	vc, sc := createTestConfig()
+
+	initialize := initializePlayer
+	initializePlayer = func(*mpv.Mpv) error { return errors.New("mpv: error running command") }
+	t.Cleanup(func() { initializePlayer = initialize })
+
+	controller, err := NewPlaybackController(vc, sc)
+	assert.Nil(t, controller, "controller should be nil")
+	assert.ErrorIs(t, err, ErrMPVInit)
+	assert.ErrorContains(t, err, "mpv: error running command")
+}
+
 // TestPlaybackFlow tests the complete playback flow
 func TestPlaybackFlow(t *testing.T) {
 	// Create test controller
@@ -132,3 +148,598 @@ func createTestController(t *testing.T) *PlaybackController {
 
 	return controller
 }
+
+// TestMapSpeedToPlaybackInverted tests that inverted mapping slows the video as speed increases
+func TestMapSpeedToPlaybackInverted(t *testing.T) {
+	// Create test configuration with inverted speed and playback bounds
+	vc, sc := createTestConfig()
+	vc.MinPlaybackSpeed = 0.5
+	vc.MaxPlaybackSpeed = 1.5
+	sc.Invert = true
+	sc.InvertReferenceSpeed = 10.0
+
+	controller := &PlaybackController{config: vc, speedConfig: sc}
+
+	// Define test cases
+	tests := []struct {
+		name       string
+		cycleSpeed float64
+		want       float64
+	}{
+		{"reference speed", 10.0, 1.0},
+		{"slower than reference", 6.0, 1.4},
+		{"faster than reference", 14.0, 0.6},
+		{"clamped to max", 1.0, 1.5},
+		{"clamped to min", 25.0, 0.5},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, controller.mapSpeedToPlayback(tt.cycleSpeed), 0.001)
+		})
+	}
+
+	// Confirm that higher speeds yield lower playback speeds
+	assert.Greater(t, controller.mapSpeedToPlayback(8.0), controller.mapSpeedToPlayback(12.0))
+}
+
+// TestPreview tests that preview mode plays the video without a speed controller
+func TestPreview(t *testing.T) {
+	// Create test controller
+	controller := createTestController(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), td.contextTimeout)
+	defer cancel()
+
+	// Start away from normal speed, so the preview must set it
+	assert.NoError(t, controller.player.SetProperty("speed", mpv.FormatDouble, 2.0))
+
+	done := make(chan error, 1)
+	go func() { done <- controller.Preview(ctx) }()
+
+	// The player runs at normal speed while previewing
+	assert.Eventually(t, func() bool {
+		playbackSpeed, err := controller.player.GetProperty("speed", mpv.FormatDouble)
+		return err == nil && playbackSpeed == previewPlaybackSpeed
+	}, td.contextTimeout/2, 10*time.Millisecond, "preview should play at normal speed")
+
+	cancel()
+	assert.NoError(t, <-done, "should run preview playback")
+}
+
+// TestRenderSpeedFilter tests speed filter templating across speed values
+func TestRenderSpeedFilter(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		name          string
+		template      string
+		cycleSpeed    float64
+		playbackSpeed float64
+		want          string
+	}{
+		{"rate placeholder", "gblur=sigma={rate}", 20.0, 1.2, "gblur=sigma=1.20"},
+		{"speed placeholder", "lavfi=[boxblur={speed}]", 12.345, 0.74, "lavfi=[boxblur=12.35]"},
+		{"both placeholders", "a={speed}:b={rate}:c={rate}", 5.0, 0.3, "a=5.00:b=0.30:c=0.30"},
+		{"no placeholders", "hflip", 18.0, 1.08, "hflip"},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, renderSpeedFilter(tt.template, tt.cycleSpeed, tt.playbackSpeed))
+		})
+	}
+
+}
+
+// TestFormatDistance tests distance formatting in the configured units
+func TestFormatDistance(t *testing.T) {
+	// Create test configuration
+	vc, sc := createTestConfig()
+
+	sc.SpeedUnits = config.SpeedUnitsKMH
+	controller := &PlaybackController{config: vc, speedConfig: sc}
+	assert.Equal(t, "12.35 km", controller.formatDistance(12345.0))
+
+	sc.SpeedUnits = config.SpeedUnitsMPH
+	controller = &PlaybackController{config: vc, speedConfig: sc}
+	assert.Equal(t, "1.00 mi", controller.formatDistance(1609.344))
+}
+
+// TestFormatDecisionTrace tests the speed to playback speed decision trace
+func TestFormatDecisionTrace(t *testing.T) {
+	// Create test configuration
+	vc, sc := createTestConfig()
+	vc.MaxPlaybackSpeed = 1.5
+	sc.SpeedUnits = config.SpeedUnitsKMH
+	controller := &PlaybackController{config: vc, speedConfig: sc}
+
+	// Unchanged transition
+	stages := playbackStages{mapped: 1.2, clamped: 1.2, lagCapped: 1.2, eased: 1.2, ramped: 1.2}
+	assert.Equal(t, "playback decision: measured 12.50 km/h, applied 12.00 km/h, mapped 1.20x, playback 1.20x",
+		controller.formatDecisionTrace(12.5, 12.0, stages))
+
+	// Clamped transition
+	stages = playbackStages{mapped: 2.0, clamped: 1.5, lagCapped: 1.5, eased: 1.5, ramped: 1.5}
+	assert.Equal(t, "playback decision: measured 21.00 km/h, applied 20.00 km/h, mapped 2.00x, clamped 1.50x, playback 1.50x",
+		controller.formatDecisionTrace(21.0, 20.0, stages))
+
+	// Lag capped, eased and ramped transition, each listed as it changes the speed
+	stages = playbackStages{mapped: 1.2, clamped: 1.2, lagCapped: 1.0, eased: 0.8, ramped: 0.4}
+	assert.Equal(t, "playback decision: measured 12.50 km/h, applied 12.00 km/h, mapped 1.20x, lag capped 1.00x, eased 0.80x, ramped 0.40x, playback 0.40x",
+		controller.formatDecisionTrace(12.5, 12.0, stages))
+
+	stages = playbackStages{mapped: 1.2, clamped: 1.2, lagCapped: 1.2, eased: 1.2, ramped: 0.6}
+	assert.Equal(t, "playback decision: measured 12.50 km/h, applied 12.00 km/h, mapped 1.20x, ramped 0.60x, playback 0.60x",
+		controller.formatDecisionTrace(12.5, 12.0, stages))
+}
+
+// TestLagMonitor tests capping the playback speed when the video player drops frames
+func TestLagMonitor(t *testing.T) {
+	var lag lagMonitor
+
+	// Occasional dropped frames don't cap the playback speed
+	capped, _ := lag.update(3, 2.0)
+	assert.False(t, capped)
+	assert.InDelta(t, 3.0, lag.apply(3.0), 0.001)
+
+	// Dropped frames at normal playback speed don't cap the playback speed
+	capped, _ = lag.update(20, 1.0)
+	assert.False(t, capped)
+	assert.InDelta(t, 3.0, lag.apply(3.0), 0.001)
+
+	// Many dropped frames at a high playback speed cap the playback speed (advising only once)
+	capped, _ = lag.update(40, 2.0)
+	assert.True(t, capped)
+	assert.InDelta(t, 1.8, lag.apply(3.0), 0.001)
+	assert.InDelta(t, 1.5, lag.apply(1.5), 0.001)
+
+	// Continued dropped frames at the capped playback speed lower the cap, but never below normal speed
+	capped, _ = lag.update(60, 1.8)
+	assert.False(t, capped)
+	assert.InDelta(t, 1.62, lag.apply(3.0), 0.001)
+	capped, _ = lag.update(500, 1.05)
+	assert.False(t, capped)
+	assert.InDelta(t, 1.0, lag.apply(3.0), 0.001)
+
+	// A dropped frame restarts the count of updates without dropped frames
+	for range lagRecoveryUpdates - 1 {
+		_, lifted := lag.update(500, 1.0)
+		assert.False(t, lifted)
+	}
+
+	_, lifted := lag.update(501, 1.0)
+	assert.False(t, lifted)
+	assert.InDelta(t, 1.0, lag.apply(3.0), 0.001)
+
+	// The cap is lifted after enough updates without dropped frames
+	for range lagRecoveryUpdates - 1 {
+		_, lifted = lag.update(501, 1.0)
+		assert.False(t, lifted)
+	}
+
+	_, lifted = lag.update(501, 1.0)
+	assert.True(t, lifted)
+	assert.InDelta(t, 3.0, lag.apply(3.0), 0.001)
+
+	// Dropped frames after the cap is lifted cap the playback speed again (advising again)
+	capped, _ = lag.update(600, 2.0)
+	assert.True(t, capped)
+	assert.InDelta(t, 1.8, lag.apply(3.0), 0.001)
+}
+
+// TestFrozenMonitor tests detecting a video player whose playback position stops advancing while
+// the video is playing
+func TestFrozenMonitor(t *testing.T) {
+	var frozen frozenMonitor
+	start := time.Now()
+	timeout := 3 * time.Second
+
+	// position returns the playback position reported after the given number of seconds: advancing
+	// for 5s, then stuck
+	position := func(secs int) float64 {
+		return float64(min(secs, 5)) * 1.5
+	}
+
+	// An advancing playback position isn't frozen
+	for secs := 0; secs <= 5; secs++ {
+		assert.False(t, frozen.update(position(secs), 1.5, start.Add(time.Duration(secs)*time.Second), timeout))
+	}
+
+	// A stuck playback position is reported frozen once the timeout is reached (advising only once)
+	for secs := 6; secs < 9; secs++ {
+		assert.False(t, frozen.update(position(secs), 1.5, start.Add(time.Duration(secs)*time.Second), timeout), "%ds", secs)
+	}
+
+	assert.True(t, frozen.update(position(9), 1.5, start.Add(9*time.Second), timeout))
+	assert.True(t, frozen.frozen)
+	assert.False(t, frozen.update(position(10), 1.5, start.Add(10*time.Second), timeout))
+
+	// An advancing playback position recovers from the frozen state
+	assert.False(t, frozen.update(8.0, 1.5, start.Add(11*time.Second), timeout))
+	assert.False(t, frozen.frozen)
+
+	// A paused video's playback position isn't expected to advance
+	for secs := 12; secs < 20; secs++ {
+		assert.False(t, frozen.update(8.0, 0.0, start.Add(time.Duration(secs)*time.Second), timeout))
+	}
+
+	assert.False(t, frozen.frozen)
+}
+
+// TestShouldPause tests that the startup pause grace applies before first motion and the riding
+// pause grace applies afterward
+func TestShouldPause(t *testing.T) {
+	// Create test configuration
+	vc, sc := createTestConfig()
+	sc.StartupPauseGraceSecs = 5.0
+	sc.RidingPauseGraceSecs = 1.0
+	controller := &PlaybackController{config: vc, speedConfig: sc}
+	start := time.Now()
+
+	// Before first motion, the (longer) startup grace applies
+	assert.False(t, controller.shouldPause(start))
+	assert.False(t, controller.shouldPause(start.Add(4*time.Second)))
+	assert.True(t, controller.shouldPause(start.Add(5*time.Second)))
+
+	// After first motion, the (shorter) riding grace applies
+	controller.markMoving()
+	stop := start.Add(60 * time.Second)
+	assert.False(t, controller.shouldPause(stop))
+	assert.False(t, controller.shouldPause(stop.Add(500*time.Millisecond)))
+	assert.True(t, controller.shouldPause(stop.Add(1*time.Second)))
+
+	// Motion resets the grace period
+	controller.markMoving()
+	assert.False(t, controller.shouldPause(stop.Add(2*time.Second)))
+	assert.True(t, controller.shouldPause(stop.Add(3*time.Second)))
+}
+
+// TestResumeRamp tests that the playback speed ramps from zero to the target speed over the resume
+// ramp time after resuming from a pause
+func TestResumeRamp(t *testing.T) {
+	// Create test configuration
+	vc, sc := createTestConfig()
+	vc.ResumeRampSecs = 2.0
+	controller := &PlaybackController{config: vc, speedConfig: sc, paused: true}
+	resumed := time.Now()
+
+	controller.startResumeRamp(resumed)
+	assert.False(t, controller.paused)
+
+	// The playback speed eases up from (nearly) zero to the target
+	assert.InDelta(t, minMPVPlaybackSpeed, controller.applyResumeRamp(1.5, resumed), 1e-9)
+	assert.InDelta(t, 0.375, controller.applyResumeRamp(1.5, resumed.Add(500*time.Millisecond)), 1e-9)
+	assert.InDelta(t, 0.75, controller.applyResumeRamp(1.5, resumed.Add(time.Second)), 1e-9)
+	assert.True(t, controller.resumeRamping(resumed.Add(1999*time.Millisecond)))
+
+	// Once the ramp completes, the target speed applies
+	assert.InDelta(t, 1.5, controller.applyResumeRamp(1.5, resumed.Add(2*time.Second)), 1e-9)
+	assert.False(t, controller.resumeRamping(resumed.Add(3*time.Second)))
+	assert.InDelta(t, 1.5, controller.applyResumeRamp(1.5, resumed.Add(3*time.Second)), 1e-9)
+
+	// Without a resume ramp, resuming applies the target speed immediately
+	controller = &PlaybackController{config: config.VideoConfig{}, speedConfig: sc, paused: true}
+	controller.startResumeRamp(resumed)
+	assert.InDelta(t, 1.5, controller.applyResumeRamp(1.5, resumed), 1e-9)
+}
+
+// TestMPVOptionsAudioOnly tests that audio-only mode omits video output and window options
+func TestMPVOptionsAudioOnly(t *testing.T) {
+	vc, sc := createTestConfig()
+	controller := &PlaybackController{config: vc, speedConfig: sc}
+	assert.NotContains(t, controller.mpvOptions(), mpvOption{"vid", "no"})
+
+	controller.config.AudioOnly = true
+	options := controller.mpvOptions()
+	assert.Contains(t, options, mpvOption{"vid", "no"})
+	assert.Contains(t, options, mpvOption{"force-window", "no"})
+	assert.Contains(t, options, mpvOption{"keep-open", "yes"})
+
+	for _, option := range options {
+		assert.NotContains(t, []string{"window-maximized", "autofit"}, option.name)
+	}
+
+	// The pause hotkey has no video window in audio-only mode
+	controller.config.PauseHotkey = "p"
+	assert.False(t, controller.pauseHotkeyEnabled())
+}
+
+// TestPausedBeforeData tests that the video stays paused until the first speed measurement arrives
+func TestPausedBeforeData(t *testing.T) {
+	controller := createTestController(t)
+	controller.speedConfig.StartupPauseGraceSecs = 60.0
+	speedController := speed.NewSpeedController(1)
+	lastSpeed := 0.0
+
+	// The startup pause grace period doesn't apply until there's speed data
+	assert.NoError(t, controller.updatePlaybackSpeed(speedController, &lastSpeed))
+	assert.True(t, controller.paused)
+
+	speedController.UpdateSpeed(5.0)
+	assert.NoError(t, controller.updatePlaybackSpeed(speedController, &lastSpeed))
+	assert.False(t, controller.paused)
+}
+
+// TestPauseHysteresis tests that a speed oscillating within the pause hysteresis band doesn't flap
+// between pause and play, while crossing the pause enter and exit speeds does
+func TestPauseHysteresis(t *testing.T) {
+	controller := createTestController(t)
+	controller.speedConfig.PauseEnterSpeed = 2.0
+	controller.speedConfig.PauseExitSpeed = 4.0
+	lastSpeed := 0.0
+
+	// Define speed series, with the expected pause state after each speed
+	steps := []struct {
+		name       string
+		speeds     []float64
+		wantPaused bool
+	}{
+		{"riding", []float64{10.0}, false},
+		{"oscillating within the band while playing", []float64{3.0, 2.5, 3.9, 2.1, 3.5}, false},
+		{"falling to the enter speed", []float64{2.0}, true},
+		{"oscillating within the band while paused", []float64{3.0, 4.0, 2.5, 3.9}, true},
+		{"rising above the exit speed", []float64{4.5}, false},
+		{"stopping", []float64{0.0}, true},
+	}
+
+	for _, step := range steps {
+
+		for _, cycleSpeed := range step.speeds {
+			assert.NoError(t, controller.checkSpeedState(cycleSpeed, &lastSpeed))
+			assert.Equal(t, step.wantPaused, controller.paused, "%s: paused mismatch at speed %.1f", step.name, cycleSpeed)
+		}
+
+	}
+
+}
+
+// TestPlaybackRate tests that the reported playback rate matches the rate computed from the cycle
+// speed after mapping and clamping, and drops to 0 while paused (including a manual pause)
+func TestPlaybackRate(t *testing.T) {
+	controller := createTestController(t)
+	controller.config.MaxPlaybackSpeed = 1.5
+	lastSpeed := 0.0
+
+	assert.Equal(t, 0.0, controller.PlaybackRate())
+
+	for _, cycleSpeed := range []float64{5.0, 10.0, 30.0} {
+		assert.NoError(t, controller.checkSpeedState(cycleSpeed, &lastSpeed))
+		assert.InDelta(t, controller.mapSpeedToPlayback(cycleSpeed), controller.PlaybackRate(), 0.0001, "speed %.1f", cycleSpeed)
+	}
+
+	assert.Equal(t, 1.5, controller.PlaybackRate())
+
+	assert.NoError(t, controller.checkSpeedState(0.0, &lastSpeed))
+	assert.Equal(t, 0.0, controller.PlaybackRate())
+
+	// A manual pause also drops the rate to 0, even with the rider still moving
+	assert.NoError(t, controller.checkSpeedState(10.0, &lastSpeed))
+	assert.NotEqual(t, 0.0, controller.PlaybackRate())
+
+	controller.manualPause = true
+	assert.NoError(t, controller.checkSpeedState(10.0, &lastSpeed))
+	assert.Equal(t, 0.0, controller.PlaybackRate())
+}
+
+// TestResumeAfterPause tests that the video resumes when the rider resumes after a pause at the
+// speed ridden before it (within the speed threshold, and without a resume ramp)
+func TestResumeAfterPause(t *testing.T) {
+	controller := createTestController(t)
+	lastSpeed := 0.0
+
+	assert.NoError(t, controller.checkSpeedState(10.0, &lastSpeed))
+	assert.NoError(t, controller.checkSpeedState(0.0, &lastSpeed))
+	assert.True(t, controller.paused, "video should pause when stopped")
+
+	assert.NoError(t, controller.checkSpeedState(10.0, &lastSpeed))
+	assert.False(t, controller.paused)
+	assert.InDelta(t, controller.mapSpeedToPlayback(10.0), controller.PlaybackRate(), 0.0001)
+
+	pause, err := controller.player.GetProperty("pause", mpv.FormatFlag)
+	assert.NoError(t, err)
+	assert.Equal(t, false, pause, "MPV should be unpaused")
+}
+
+// TestInterpolateBetweenSamples tests that the applied playback rate eases smoothly toward a new
+// sparse speed sample, without overshooting it, rather than stepping straight to it
+func TestInterpolateBetweenSamples(t *testing.T) {
+	controller := createTestController(t)
+	controller.speedConfig.InterpolateBetweenSamples = true
+	lastSpeed := 0.0
+
+	// The first sample after a pause starts at its playback rate
+	assert.NoError(t, controller.checkSpeedState(5.0, &lastSpeed))
+	assert.InDelta(t, controller.mapSpeedToPlayback(5.0), controller.PlaybackRate(), 0.0001)
+
+	// Later updates ease toward the next sample's playback rate
+	from, target := controller.PlaybackRate(), controller.mapSpeedToPlayback(15.0)
+	previous := from
+
+	for range 10 {
+		assert.NoError(t, controller.checkSpeedState(15.0, &lastSpeed))
+
+		rate := controller.PlaybackRate()
+		assert.GreaterOrEqual(t, rate, previous, "playback rate should only move toward the target")
+		assert.LessOrEqual(t, rate, target, "playback rate should not overshoot the target")
+		assert.LessOrEqual(t, rate-previous, (target-from)/2+0.0001, "playback rate should not step to the target")
+		previous = rate
+	}
+
+	assert.Equal(t, target, controller.PlaybackRate(), "playback rate should settle on the target")
+	assert.False(t, controller.interpolating())
+
+	// Without interpolation, the playback rate steps straight to the new sample's playback rate
+	controller.speedConfig.InterpolateBetweenSamples = false
+	assert.NoError(t, controller.checkSpeedState(5.0, &lastSpeed))
+	assert.InDelta(t, controller.mapSpeedToPlayback(5.0), controller.PlaybackRate(), 0.0001)
+}
+
+// TestZeroDeadband tests that speeds below the zero deadband report zero (pausing the video), while
+// speeds at or above it pass through
+func TestZeroDeadband(t *testing.T) {
+	controller := createTestController(t)
+	controller.speedConfig.ZeroDeadband = 0.5
+
+	assert.Equal(t, 0.0, controller.applyZeroDeadband(0.49))
+	assert.Equal(t, 0.5, controller.applyZeroDeadband(0.5))
+	assert.Equal(t, 0.51, controller.applyZeroDeadband(0.51))
+
+	// A speed trailing off just above zero pauses the video
+	speedController := speed.NewSpeedController(1)
+	lastSpeed := 0.0
+
+	speedController.UpdateSpeed(speed.FromSpeedUnits(0.3, controller.speedConfig.SpeedUnits))
+	assert.NoError(t, controller.updatePlaybackSpeed(speedController, &lastSpeed))
+	assert.True(t, controller.paused)
+
+	speedController.UpdateSpeed(speed.FromSpeedUnits(0.8, controller.speedConfig.SpeedUnits))
+	assert.NoError(t, controller.updatePlaybackSpeed(speedController, &lastSpeed))
+	assert.False(t, controller.paused)
+}
+
+// TestManualPause tests that a manual (hotkey) pause takes precedence over nonzero sensor speeds
+// until released
+func TestManualPause(t *testing.T) {
+	controller := createTestController(t)
+	controller.config.PauseHotkey = "p"
+	assert.NoError(t, controller.configureMPVPlayer())
+
+	// Start riding
+	lastSpeed := 0.0
+	assert.NoError(t, controller.checkSpeedState(10.0, &lastSpeed))
+	assert.False(t, controller.paused)
+	assert.Equal(t, 10.0, lastSpeed)
+
+	// While manually paused, incoming speeds don't resume playback
+	controller.setManualPause(true, &lastSpeed)
+
+	for _, cycleSpeed := range []float64{12.0, 20.0, 5.0} {
+		assert.NoError(t, controller.checkSpeedState(cycleSpeed, &lastSpeed))
+		assert.True(t, controller.paused, "speed %.1f resumed a manual pause", cycleSpeed)
+		assert.Equal(t, 10.0, lastSpeed)
+	}
+
+	// Releasing the manual pause resumes playback at the current speed
+	controller.setManualPause(false, &lastSpeed)
+	assert.NoError(t, controller.checkSpeedState(5.0, &lastSpeed))
+	assert.False(t, controller.paused)
+	assert.Equal(t, 5.0, lastSpeed)
+}
+
+// TestRideMarker tests binding the marker hotkey and the ride marker log message
+func TestRideMarker(t *testing.T) {
+	controller := createTestController(t)
+	controller.config.MarkerHotkey = "m"
+	assert.True(t, controller.markerHotkeyEnabled())
+	assert.NoError(t, controller.configureMPVPlayer())
+
+	controller.config.AudioOnly = true
+	assert.False(t, controller.markerHotkeyEnabled())
+
+	now := time.Date(2024, 6, 1, 8, 15, 30, 0, time.UTC)
+	assert.Equal(t, "ride marker at 08:15:30, distance 12.35 km", controller.formatMarker(now, 12345.0))
+}
+
+// TestCalibrateReference tests deriving the reference speed and speed multiplier from a window of
+// calibration speed samples
+func TestCalibrateReference(t *testing.T) {
+	// Riding at the pace of the video (about 15), with brief stops
+	samples := []float64{0.0, 0.0, 14.0, 15.0, 16.0, 15.5, 14.5, 15.0, 0.0, 15.0, 14.0, 16.0, 15.0, 15.0}
+
+	referenceSpeed, multiplier, err := calibrateReference(samples)
+	assert.NoError(t, err)
+	assert.InDelta(t, 15.0, referenceSpeed, 0.0001)
+	assert.InDelta(t, 10.0/15.0, multiplier, 0.0001)
+
+	// The derived multiplier maps the reference speed to normal playback speed
+	vc, sc := createTestConfig()
+	vc.SpeedMultiplier = multiplier
+	controller := &PlaybackController{config: vc, speedConfig: sc}
+	assert.InDelta(t, 1.0, controller.mapSpeedToPlayback(referenceSpeed), 0.0001)
+
+	// Mostly stopped, or too few samples
+	_, _, err = calibrateReference([]float64{0.0, 0.0, 0.0, 15.0, 15.0, 0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 15.0})
+	assert.Error(t, err)
+
+	_, _, err = calibrateReference([]float64{15.0, 15.0, 15.0})
+	assert.Error(t, err)
+}
+
+// TestEndOfVideo tests the end of video handling for each on_end behavior
+func TestEndOfVideo(t *testing.T) {
+	// Define test cases
+	tests := []struct {
+		onEnd        string
+		wantLoopFile string
+		wantErr      error
+		wantHold     bool
+	}{
+		{onEnd: config.OnEndStop, wantLoopFile: "no", wantErr: ErrVideoComplete},
+		{onEnd: config.OnEndHold, wantLoopFile: "no", wantHold: true},
+		{onEnd: config.OnEndLoop, wantLoopFile: "inf", wantErr: ErrVideoComplete},
+	}
+
+	// Run tests
+	for _, tt := range tests {
+		t.Run(tt.onEnd, func(t *testing.T) {
+			controller := createTestController(t)
+			controller.config.OnEnd = tt.onEnd
+
+			assert.Equal(t, tt.wantLoopFile, controller.loopFile())
+			assert.NoError(t, controller.configureMPVPlayer())
+
+			// Holding on the last frame waits for the context to be cancelled
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := controller.endOfVideo(ctx)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantHold, time.Since(start) >= 50*time.Millisecond, "hold mismatch")
+		})
+	}
+
+}
+
+// TestCheckDisplay tests the behavior without a display: failing with an explanation, or falling
+// back to audio-only playback if configured
+func TestCheckDisplay(t *testing.T) {
+	// Stub the environment of a headless Linux system, and of a Linux desktop
+	headless := func(string) string { return "" }
+	wayland := func(name string) string {
+
+		if name == "WAYLAND_DISPLAY" {
+			return "wayland-0"
+		}
+
+		return ""
+	}
+
+	assert.False(t, displayAvailable("linux", headless))
+	assert.True(t, displayAvailable("linux", wayland))
+	assert.True(t, displayAvailable("darwin", headless), "macOS doesn't need a display server")
+
+	// Without a display, fail with an explanation by default
+	vc, _ := createTestConfig()
+	vc.Headless = config.HeadlessFail
+	err := checkDisplay(&vc, false)
+	assert.ErrorIs(t, err, ErrNoDisplay)
+	assert.Contains(t, err.Error(), "audio_only")
+
+	// Without a display, play only the audio if configured
+	vc.Headless = config.HeadlessAudioOnly
+	assert.NoError(t, checkDisplay(&vc, false))
+	assert.True(t, vc.AudioOnly)
+
+	// Audio-only playback, or a display, needs no fallback
+	vc, _ = createTestConfig()
+	vc.AudioOnly = true
+	assert.NoError(t, checkDisplay(&vc, false))
+
+	vc.AudioOnly = false
+	assert.NoError(t, checkDisplay(&vc, true))
+	assert.False(t, vc.AudioOnly)
+}